@@ -0,0 +1,115 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/init.html#c.PyThreadState_SetAsyncExc
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+
+// Attaches the current thread to the interpreter, which must not be current, and raises the
+// exception asynchronously in the other thread
+static void py4go_interruptThread(PyInterpreterState *interpreter, unsigned long threadID, PyObject *exception) {
+	PyThreadState *state = PyThreadState_New(interpreter);
+	PyEval_RestoreThread(state);
+	PyThreadState_SetAsyncExc(threadID, exception);
+	PyThreadState_Clear(state);
+	PyThreadState_DeleteCurrent();
+}
+*/
+import "C"
+
+// Like Call but interrupts the Python code when the context is done, by raising an exception in
+// its thread. In that case the returned error wraps the context's error, so you can check it with
+// errors.Is(err, context.Canceled) or errors.Is(err, context.DeadlineExceeded).
+//
+// The exception is of type "py4go.Cancelled" (one per interpreter), which inherits from
+// BaseException so that it is not caught by "except Exception".
+//
+// Note that Python only checks for the exception between bytecode instructions, so code that is
+// blocked in a C function (e.g. time.sleep or a socket read) will only be interrupted when that
+// function returns. Also, Python code may catch the exception (via "except BaseException" or a
+// bare "except") and keep running.
+//
+// Must be called while holding the GIL. Works in sub-interpreters, too (see Interpreter.Do).
+func (self *Reference) CallContext(context_ context.Context, args ...interface{}) (*Reference, error) {
+	if err := context_.Err(); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := getExcCancelled()
+	if err != nil {
+		return nil, err
+	}
+
+	// The thread ID must remain stable during the call
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	threadID := C.PyThread_get_thread_ident()
+	interpreter := getCurrentInterpreter()
+
+	var lock sync.Mutex
+	done := false
+	interrupting := false
+
+	stop := make(chan struct{})
+	interrupted := make(chan struct{})
+
+	go func() {
+		select {
+		case <-context_.Done():
+			lock.Lock()
+			interrupting = !done
+			lock.Unlock()
+
+			if interrupting {
+				runtime.LockOSThread()
+				defer runtime.UnlockOSThread()
+
+				C.py4go_interruptThread(interpreter, threadID, cancelled.Object)
+				close(interrupted)
+			}
+		case <-stop:
+		}
+	}()
+
+	r, err := self.Call(args...)
+
+	lock.Lock()
+	done = true
+	interrupting_ := interrupting
+	lock.Unlock()
+	close(stop)
+
+	if interrupting_ {
+		// The watcher needs the GIL, and we must not return before it is done with the interpreter
+		threadState := SaveThreadState()
+		<-interrupted
+		threadState.Restore()
+
+		// The exception may still be pending if the call returned before Python noticed it
+		C.PyThreadState_SetAsyncExc(threadID, nil)
+
+		if exception, ok := err.(*Exception); ok && exception.Matches(cancelled) {
+			exception.Release()
+			return nil, fmt.Errorf("Python call interrupted: %w", context_.Err())
+		}
+	}
+
+	return r, err
+}
+
+// The type belongs to the interpreter
+func getExcCancelled() (*Reference, error) {
+	return getInterpreterCached("py4go.Cancelled", func() (*Reference, error) {
+		return NewExceptionType("py4go.Cancelled", ExcBaseException)
+	})
+}
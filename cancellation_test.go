@@ -0,0 +1,161 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const cancellationSource = `
+def spin(swallow):
+    while True:
+        try:
+            while True:
+                pass
+        except Exception:
+            # Cancellation is not an Exception
+            swallow.append(True)
+
+def add(a, b):
+    return a + b
+`
+
+func newCancellationGlobals(t *testing.T) *Reference {
+	t.Helper()
+
+	globals := newGlobals(t, nil)
+	if err := Exec(cancellationSource, globals, nil); err != nil {
+		globals.Release()
+		t.Fatal(err)
+	}
+	return globals
+}
+
+func getGlobal(t *testing.T, globals *Reference, name string) *Reference {
+	t.Helper()
+
+	if value, err := Eval(name, globals, nil); err == nil {
+		return value
+	} else {
+		t.Fatal(err)
+		return nil
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newCancellationGlobals(t)
+		defer globals.Release()
+
+		add := getGlobal(t, globals, "add")
+		defer add.Release()
+
+		if result, err := add.CallContext(context.Background(), 1, 2); err == nil {
+			assertPython(t, result, "value == 3")
+			result.Release()
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestCallContextDeadline(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newCancellationGlobals(t)
+		defer globals.Release()
+
+		spin := getGlobal(t, globals, "spin")
+		defer spin.Release()
+
+		swallow := getGlobal(t, globals, "[]")
+		defer swallow.Release()
+
+		context_, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if result, err := spin.CallContext(context_, swallow); err == nil {
+			result.Release()
+			t.Fatal("expected an error")
+		} else {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected context.DeadlineExceeded, got %v", err)
+			}
+			releaseError(err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("took %s", elapsed)
+		}
+		assertPython(t, swallow, "value == []")
+
+		// The interpreter is still usable
+		if HasException() {
+			t.Error("expected no pending exception")
+		}
+		if result, err := Eval("1 + 1", nil, nil); err == nil {
+			result.Release()
+		} else {
+			t.Error(err)
+		}
+	})
+}
+
+func TestCallContextCancel(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newCancellationGlobals(t)
+		defer globals.Release()
+
+		spin := getGlobal(t, globals, "spin")
+		defer spin.Release()
+
+		swallow := getGlobal(t, globals, "[]")
+		defer swallow.Release()
+
+		context_, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		if result, err := spin.CallContext(context_, swallow); err == nil {
+			result.Release()
+			t.Fatal("expected an error")
+		} else {
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+func TestCallContextDone(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newCancellationGlobals(t)
+		defer globals.Release()
+
+		add := getGlobal(t, globals, "add")
+		defer add.Release()
+
+		context_, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// The function is not called
+		if result, err := add.CallContext(context_, 1, "x"); err == nil {
+			result.Release()
+			t.Fatal("expected an error")
+		} else if !errors.Is(err, context.Canceled) || errors.Is(err, ErrTypeError) {
+			t.Errorf("expected only context.Canceled, got %v", err)
+		}
+	})
+}
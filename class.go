@@ -0,0 +1,451 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/type.html#creating-heap-allocated-types
+//   https://docs.python.org/3/c-api/structures.html#c.PyGetSetDef
+//   https://docs.python.org/3/c-api/method.html#instance-method-objects
+
+// Note: cgo exports cannot be in the same file as cgo preamble functions,
+// which is why the Go side of the slots is in "export.go"
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+
+uintptr_t py4go_newGoObject(PyTypeObject *type, PyObject *args, PyObject *kw);
+void py4go_releaseGoObject(uintptr_t handle);
+PyObject *py4go_reprGoObject(uintptr_t handle);
+PyObject *py4go_getGoObjectAttribute(uintptr_t handle, uintptr_t attribute);
+int py4go_setGoObjectAttribute(uintptr_t handle, PyObject *value, uintptr_t attribute);
+
+typedef struct {
+	PyObject_HEAD
+	uintptr_t handle;
+} py4go_GoObject;
+
+static PyObject *py4go_allocGoObject(PyTypeObject *type, uintptr_t handle) {
+	PyObject *self = type->tp_alloc(type, 0);
+	if (self != NULL)
+		((py4go_GoObject *) self)->handle = handle;
+	return self;
+}
+
+static uintptr_t py4go_getGoObjectHandle(PyObject *self) {
+	return ((py4go_GoObject *) self)->handle;
+}
+
+// newfunc signature
+static PyObject *py4go_goObjectNew(PyTypeObject *type, PyObject *args, PyObject *kw) {
+	uintptr_t handle = py4go_newGoObject(type, args, kw);
+	if (handle == 0)
+		return NULL;
+	PyObject *self = py4go_allocGoObject(type, handle);
+	if (self == NULL)
+		py4go_releaseGoObject(handle);
+	return self;
+}
+
+// destructor signature
+static void py4go_goObjectDealloc(PyObject *self) {
+	PyTypeObject *type = Py_TYPE(self);
+	// Calls __del__, if it was added via AddMethod
+	if ((type->tp_finalize != NULL) && (PyObject_CallFinalizerFromDealloc(self) < 0))
+		// Resurrected
+		return;
+	uintptr_t handle = ((py4go_GoObject *) self)->handle;
+	if (handle != 0)
+		py4go_releaseGoObject(handle);
+	type->tp_free(self);
+	// Instances of heap types own a reference to their type
+	Py_DECREF(type);
+}
+
+// reprfunc signature
+static PyObject *py4go_goObjectRepr(PyObject *self) {
+	return py4go_reprGoObject(((py4go_GoObject *) self)->handle);
+}
+
+// getter signature
+static PyObject *py4go_goObjectGet(PyObject *self, void *closure) {
+	return py4go_getGoObjectAttribute(((py4go_GoObject *) self)->handle, (uintptr_t) closure);
+}
+
+// setter signature
+static int py4go_goObjectSet(PyObject *self, PyObject *value, void *closure) {
+	return py4go_setGoObjectAttribute(((py4go_GoObject *) self)->handle, value, (uintptr_t) closure);
+}
+
+static void py4go_setGetSetDef(PyGetSetDef *defs, int index, char *name, uintptr_t closure) {
+	defs[index].name = name;
+	defs[index].get = py4go_goObjectGet;
+	defs[index].set = py4go_goObjectSet;
+	defs[index].closure = (void *) closure;
+}
+
+// Wraps the function so that it binds to instances, like a method defined in Python
+static int py4go_setGoClassMethod(PyObject *type, char *name, PyObject *function) {
+	PyObject *method = PyInstanceMethod_New(function);
+	if (method == NULL)
+		return -1;
+	int r = PyObject_SetAttrString(type, name, method);
+	Py_DECREF(method);
+	return r;
+}
+
+static PyObject *py4go_newGoClass(char *name, PyGetSetDef *defs, int hasRepr) {
+	PyType_Slot slots[] = {
+		{Py_tp_new, py4go_goObjectNew},
+		{Py_tp_dealloc, py4go_goObjectDealloc},
+		{Py_tp_getset, defs},
+		{hasRepr ? Py_tp_repr : 0, hasRepr ? py4go_goObjectRepr : NULL},
+		{0, NULL}
+	};
+
+	PyType_Spec spec = {
+		.name = name,
+		.basicsize = sizeof(py4go_GoObject),
+		.flags = Py_TPFLAGS_DEFAULT,
+		.slots = slots
+	};
+
+	return PyType_FromSpec(&spec);
+}
+*/
+import "C"
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+var goClasses = make(map[reflect.Type]*GoClass)
+var goClassesByObject = make(map[*C.PyTypeObject]*GoClass)
+var goClassesLock sync.RWMutex
+
+//
+// GoClass
+//
+
+// A Python heap type whose instances wrap Go values. The type objects are never freed.
+type GoClass struct {
+	Name      string
+	Type      reflect.Type // always a pointer type
+	Reference *Reference
+
+	attributes []*goAttribute
+}
+
+type goAttribute struct {
+	name  string
+	field []int
+}
+
+// Creates a Python class for the type of the prototype, which is usually a pointer to a struct.
+// Instances hold a pointer to the Go value, so changes made in Python are visible in Go and vice
+// versa.
+//
+// Exported fields become properties (named by "py" tag or else in snake case) and exported
+// methods become Python methods (in snake case). If the type implements fmt.Stringer it is used
+// for __repr__. Other special methods can be added via AddMethod. Calling the class from Python
+// creates a zero value, optionally with keyword arguments for initializing fields.
+//
+// The name should be qualified with the module name, e.g. "api.Person".
+func NewGoClass(name string, prototype interface{}) (*GoClass, error) {
+	type_ := reflect.TypeOf(prototype)
+	if type_ == nil {
+		return nil, fmt.Errorf("unsupported class type: %T", prototype)
+	}
+	if type_.Kind() != reflect.Ptr {
+		type_ = reflect.PtrTo(type_)
+	}
+
+	goClassesLock.Lock()
+	defer goClassesLock.Unlock()
+
+	if _, ok := goClasses[type_]; ok {
+		return nil, fmt.Errorf("class already exists for type: %s", type_)
+	}
+
+	if self, err := newGoClass(name, type_, nil); err == nil {
+		// Never freed, so not a leak
+		self.Reference.untrack()
+
+		goClasses[type_] = self
+		goClassesByObject[(*C.PyTypeObject)(unsafe.Pointer(self.Reference.Object))] = self
+		return self, nil
+	} else {
+		return nil, err
+	}
+}
+
+// The extra methods are added via AddMethod
+func newGoClass(name string, type_ reflect.Type, methods map[string]interface{}) (*GoClass, error) {
+	self := GoClass{
+		Name: name,
+		Type: type_,
+	}
+
+	names := make(map[string]bool)
+
+	var methods_ []reflect.Method
+	for index := 0; index < type_.NumMethod(); index++ {
+		method := type_.Method(index)
+		if (method.Name == "String") && type_.Implements(stringerType) {
+			// Will be __repr__
+			continue
+		}
+		names[toSnakeCase(method.Name)] = true
+		methods_ = append(methods_, method)
+	}
+
+	if elem := type_.Elem(); elem.Kind() == reflect.Struct {
+		self.addFields(elem, nil, names)
+	}
+
+	defs := (*C.PyGetSetDef)(C.calloc(C.size_t(len(self.attributes)+1), C.size_t(unsafe.Sizeof(C.PyGetSetDef{}))))
+	for index, attribute := range self.attributes {
+		// The names must remain allocated for the lifetime of the type
+		C.py4go_setGetSetDef(defs, C.int(index), C.CString(attribute.name), C.uintptr_t(cgo.NewHandle(attribute)))
+	}
+
+	hasRepr := 0
+	if type_.Implements(stringerType) {
+		hasRepr = 1
+	}
+
+	// The name must remain allocated for the lifetime of the type
+	if typeObject := C.py4go_newGoClass(C.CString(name), defs, C.int(hasRepr)); typeObject != nil {
+		self.Reference = NewReference(typeObject)
+
+		for _, method := range methods_ {
+			if err := self.AddMethod(toSnakeCase(method.Name), method.Func.Interface()); err != nil {
+				self.Reference.Release()
+				return nil, err
+			}
+		}
+
+		for name_, method := range methods {
+			if err := self.AddMethod(name_, method); err != nil {
+				self.Reference.Release()
+				return nil, err
+			}
+		}
+
+		return &self, nil
+	} else {
+		return nil, GetError()
+	}
+}
+
+// Adds a new class to the module, qualifying its name with the module name
+func (self *Reference) AddModuleGoClass(name string, prototype interface{}) (*GoClass, error) {
+	if moduleName, err := self.GetModuleName(); err == nil {
+		if class, err := NewGoClass(moduleName+"."+name, prototype); err == nil {
+			if err := self.SetAttr(name, class.Reference); err == nil {
+				return class, nil
+			} else {
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+func (self *GoClass) addFields(type_ reflect.Type, index []int, names map[string]bool) {
+	for index_ := 0; index_ < type_.NumField(); index_++ {
+		field := type_.Field(index_)
+		fieldIndex := append(append([]int{}, index...), index_)
+
+		if field.Anonymous && (field.Type.Kind() == reflect.Struct) && (field.Tag.Get(TAG) == "") {
+			// Flatten embedded structs (but not embedded pointers, which may be nil)
+			self.addFields(field.Type, fieldIndex, names)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			// Unexported
+			continue
+		}
+
+		if name, _, skip := parseFieldTag(field); !skip {
+			if field.Tag.Get(TAG) == "" {
+				name = toSnakeCase(name)
+			}
+			if !names[name] {
+				names[name] = true
+				self.attributes = append(self.attributes, &goAttribute{name: name, field: fieldIndex})
+			}
+		}
+	}
+}
+
+// Adds a Python method to the class. The name can be any name, including special method names such
+// as "__iter__" or "__len__". The function's first argument is the instance, either as the Go
+// value or as the *Reference.
+func (self *GoClass) AddMethod(name string, function interface{}) error {
+	function_ := reflect.ValueOf(function)
+	if (function_.Kind() != reflect.Func) || (function_.Type().NumIn() == 0) {
+		return fmt.Errorf("not a method function: %T", function)
+	}
+
+	if function__, err := newGoMethod(self.Name+"."+name, function_, self.Type); err == nil {
+		defer function__.Release()
+
+		return addInstanceMethod(self.Reference, name, function__)
+	} else {
+		return err
+	}
+}
+
+// Adds the function to the class so that it binds to instances, like a method defined in Python.
+// The class does not have to be a Go class.
+func addInstanceMethod(class *Reference, name string, function *Reference) error {
+	name_ := C.CString(name)
+	defer C.free(unsafe.Pointer(name_))
+
+	if C.py4go_setGoClassMethod(class.Object, name_, function.Object) == 0 {
+		return nil
+	} else {
+		return GetError()
+	}
+}
+
+func (self *GoClass) getAttribute(name string) *goAttribute {
+	for _, attribute := range self.attributes {
+		if attribute.name == name {
+			return attribute
+		}
+	}
+	return nil
+}
+
+// Creates a Python instance that wraps the value. If the value is not a pointer it is copied.
+func (self *GoClass) NewObject(value interface{}) (*Reference, error) {
+	value_ := reflect.ValueOf(value)
+	if value_.Type() != self.Type {
+		if value_.Type() == self.Type.Elem() {
+			pointer := reflect.New(value_.Type())
+			pointer.Elem().Set(value_)
+			value_ = pointer
+		} else {
+			return nil, fmt.Errorf("value of type %s is not a %s", value_.Type(), self.Type)
+		}
+	}
+
+	handle := cgo.NewHandle(value_)
+	if object := C.py4go_allocGoObject((*C.PyTypeObject)(unsafe.Pointer(self.Reference.Object)), C.uintptr_t(handle)); object != nil {
+		return NewReference(object), nil
+	} else {
+		handle.Delete()
+		return nil, GetError()
+	}
+}
+
+func (self *goAttribute) get(value reflect.Value) (*Reference, error) {
+	return newReferenceFromValue(value.Elem().FieldByIndex(self.field))
+}
+
+func (self *goAttribute) set(value reflect.Value, reference *Reference) error {
+	field := value.Elem().FieldByIndex(self.field)
+	fieldValue := reflect.New(field.Type()).Elem()
+	if err := unmarshal(reference, fieldValue, self.name); err == nil {
+		field.Set(fieldValue)
+		return nil
+	} else {
+		return newTypeError("%s", err.Error())
+	}
+}
+
+// Wraps the value with the class registered for its type
+func NewGoObject(value interface{}) (*Reference, error) {
+	if class := getGoClass(reflect.TypeOf(value)); class != nil {
+		return class.NewObject(value)
+	} else {
+		return nil, fmt.Errorf("no class for type: %T", value)
+	}
+}
+
+func (self *Reference) IsGoObject() bool {
+	_, ok := self.getGoObjectValue()
+	return ok
+}
+
+// Returns the wrapped Go value (always a pointer)
+func (self *Reference) ToGoObject() (interface{}, error) {
+	if value, ok := self.getGoObjectValue(); ok {
+		return value.Interface(), nil
+	} else {
+		return nil, fmt.Errorf("not a Go object: %s", self.Type().Name())
+	}
+}
+
+func (self *Reference) getGoObjectValue() (reflect.Value, bool) {
+	if getGoClassByObject(self.Object.ob_type) != nil {
+		return getGoObjectValue(C.py4go_getGoObjectHandle(self.Object)), true
+	} else {
+		return reflect.Value{}, false
+	}
+}
+
+// Accepts both T and *T
+func getGoClass(type_ reflect.Type) *GoClass {
+	if type_ == nil {
+		return nil
+	}
+
+	goClassesLock.RLock()
+	defer goClassesLock.RUnlock()
+
+	if type_.Kind() != reflect.Ptr {
+		type_ = reflect.PtrTo(type_)
+	}
+	return goClasses[type_]
+}
+
+// Like NewGoClass but the class belongs to the current interpreter and is not registered for the
+// type, so it is not used by NewReferenceFromValue or NewGoObject. The extra methods are added via
+// AddMethod. For our own classes.
+func getHelperGoClass(name string, prototype interface{}, methods map[string]interface{}) (*GoClass, error) {
+	if typeObject, err := getInterpreterCached(name, func() (*Reference, error) {
+		if self, err := newGoClass(name, reflect.TypeOf(prototype), methods); err == nil {
+			goClassesLock.Lock()
+			defer goClassesLock.Unlock()
+
+			goClassesByObject[(*C.PyTypeObject)(unsafe.Pointer(self.Reference.Object))] = self
+			return self.Reference, nil
+		} else {
+			return nil, err
+		}
+	}); err == nil {
+		return getGoClassByObject((*C.PyTypeObject)(unsafe.Pointer(typeObject.Object))), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Called when the interpreter that owns a helper class is finalized
+func unregisterHelperGoClass(typeObject *C.PyObject) {
+	goClassesLock.Lock()
+	defer goClassesLock.Unlock()
+
+	delete(goClassesByObject, (*C.PyTypeObject)(unsafe.Pointer(typeObject)))
+}
+
+func getGoClassByObject(typeObject *C.PyTypeObject) *GoClass {
+	goClassesLock.RLock()
+	defer goClassesLock.RUnlock()
+
+	return goClassesByObject[typeObject]
+}
+
+func getGoObjectValue(handle C.uintptr_t) reflect.Value {
+	return cgo.Handle(handle).Value().(reflect.Value)
+}
@@ -0,0 +1,156 @@
+package python
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+type testAnimal struct {
+	Name string `py:"name"`
+	Legs int
+	Tags []string
+
+	secret int
+}
+
+func (self *testAnimal) Speak(sound string) string {
+	return self.Name + " says " + sound
+}
+
+// fmt.Stringer interface
+func (self *testAnimal) String() string {
+	return "Animal(" + self.Name + ")"
+}
+
+var testAnimalClass *GoClass
+var testAnimalClassOnce sync.Once
+
+// Classes are registered for their Go type, so we can create them only once
+func getTestAnimalClass(t *testing.T) *GoClass {
+	t.Helper()
+
+	var err error
+	testAnimalClassOnce.Do(func() {
+		if testAnimalClass, err = NewGoClass("test.Animal", (*testAnimal)(nil)); err == nil {
+			err = testAnimalClass.AddMethod("__len__", func(self *testAnimal) int {
+				return self.Legs
+			})
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testAnimalClass == nil {
+		t.Fatal("test class was not created")
+	}
+	return testAnimalClass
+}
+
+func TestGoClass(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		getTestAnimalClass(t)
+
+		animal := &testAnimal{Name: "Rex", Legs: 4, Tags: []string{"good"}, secret: 1}
+		object, err := NewReferenceFromValue(animal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer object.Release()
+
+		assertPython(t, object, "type(value).__name__ == 'Animal' and type(value).__module__ == 'test'")
+		assertPython(t, object, "value.name == 'Rex' and value.legs == 4 and value.tags == ['good']")
+		assertPython(t, object, "value.speak('woof') == 'Rex says woof'")
+		assertPython(t, object, "repr(value) == 'Animal(Rex)' and len(value) == 4")
+		assertPython(t, object, "not hasattr(value, 'secret')")
+
+		// The instance wraps our pointer
+		assertPython(t, object, "setattr(value, 'legs', 3) is None")
+		if animal.Legs != 3 {
+			t.Errorf("expected the change to be visible in Go, got %d", animal.Legs)
+		}
+
+		animal.Name = "Max"
+		assertPython(t, object, "value.name == 'Max'")
+
+		if value, err := object.ToGoObject(); (err != nil) || (value != animal) {
+			t.Errorf("expected our pointer, got %v, %v", value, err)
+		}
+	})
+}
+
+func TestGoClassFromPython(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		class := getTestAnimalClass(t)
+
+		object := eval(t, "Animal(name='Tom', legs=2)", map[string]interface{}{"Animal": class.Reference})
+		defer object.Release()
+
+		if !object.IsGoObject() {
+			t.Fatal("expected a Go object")
+		}
+		if value, err := object.ToGoObject(); err == nil {
+			if animal, ok := value.(*testAnimal); !ok || (animal.Name != "Tom") || (animal.Legs != 2) {
+				t.Errorf("unexpected %#v", value)
+			}
+		} else {
+			t.Fatal(err)
+		}
+
+		// Not a Go object
+		notGo := eval(t, "object()", nil)
+		defer notGo.Release()
+		if notGo.IsGoObject() {
+			t.Error("expected not a Go object")
+		}
+		if _, err := notGo.ToGoObject(); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestGoClassErrors(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		getTestAnimalClass(t)
+
+		object, err := NewGoObject(&testAnimal{Name: "Rex"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer object.Release()
+
+		globals := newGlobals(t, map[string]interface{}{"value": object})
+		defer globals.Release()
+
+		for expression, target := range map[string]error{
+			"setattr(value, 'legs', 'x')": ErrTypeError,
+			"value.speak()":               ErrTypeError,
+			"value.missing":               ErrAttributeError,
+		} {
+			if result, err := Eval(expression, globals, nil); err == nil {
+				result.Release()
+				t.Errorf("%s: expected an error", expression)
+			} else {
+				if !errors.Is(err, target) {
+					t.Errorf("%s: expected %s, got %s", expression, target, err)
+				}
+				releaseError(err)
+			}
+		}
+
+		// Already registered
+		if _, err := NewGoClass("test.Animal", testAnimal{}); err == nil {
+			t.Error("expected an error")
+		}
+
+		if _, err := NewGoObject(1); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -3,6 +3,10 @@ package python
 // See:
 //   https://docs.python.org/3/c-api/init.html
 
+import (
+	"runtime"
+)
+
 /*
 #define PY_SSIZE_T_CLEAN
 #include <Python.h>
@@ -40,3 +44,39 @@ func EnsureGilState() *GilState {
 func (self *GilState) Release() {
 	C.PyGILState_Release(self.State)
 }
+
+//
+// Goroutines
+//
+
+// Runs the function while holding the GIL. The goroutine is locked to its OS thread for the
+// duration, because the GIL state belongs to the thread. The GIL is released and the thread is
+// unlocked even if the function panics.
+//
+// Can be called from any goroutine, including ones that already hold the GIL.
+func WithGIL(f func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	gilState := EnsureGilState()
+	defer gilState.Release()
+
+	return f()
+}
+
+// Runs the function with WithGIL in a new goroutine. The returned channel is closed when the
+// function returns.
+func Go(f func()) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		WithGIL(func() error {
+			f()
+			return nil
+		})
+	}()
+
+	return done
+}
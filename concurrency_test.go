@@ -0,0 +1,94 @@
+package python
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Fails the test if the GIL cannot be acquired by another goroutine
+func assertGILReleased(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-Go(func() {}):
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the GIL to be released")
+	}
+}
+
+func TestWithGIL(t *testing.T) {
+	CheckLeaks(t)
+
+	expected := errors.New("expected")
+	if err := WithGIL(func() error {
+		// Nested
+		return WithGIL(func() error {
+			eval(t, "1", nil).Release()
+			return expected
+		})
+	}); err != expected {
+		t.Errorf("expected the function's error, got %v", err)
+	}
+
+	assertGILReleased(t)
+}
+
+func TestWithGILPanic(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+
+		WithGIL(func() error {
+			panic("oops")
+		})
+	}()
+
+	assertGILReleased(t)
+}
+
+func TestGo(t *testing.T) {
+	CheckLeaks(t)
+
+	var append_ *Reference
+	withGIL(t, func() {
+		list := eval(t, "[]", nil)
+		defer list.Release()
+
+		var err error
+		if append_, err = list.GetAttr("append"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	const count = 20
+	var dones []<-chan struct{}
+	for index := 0; index < count; index++ {
+		index := index
+		dones = append(dones, Go(func() {
+			if r, err := append_.Call(index); err == nil {
+				r.Release()
+			} else {
+				t.Error(err)
+			}
+		}))
+	}
+
+	for _, done := range dones {
+		<-done
+	}
+
+	withGIL(t, func() {
+		defer append_.Release()
+
+		list, err := append_.GetAttr("__self__")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertPython(t, list, "sorted(value) == list(range(20))")
+		list.Release()
+	})
+}
@@ -0,0 +1,265 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/init_config.html
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+
+static PyStatus py4go_appendWideString(PyWideStringList *list, const char *value) {
+	wchar_t *value_ = Py_DecodeLocale(value, NULL);
+	if (value_ == NULL)
+		return PyStatus_Error("cannot decode string");
+	PyStatus status = PyWideStringList_Append(list, value_);
+	PyMem_RawFree(value_);
+	return status;
+}
+*/
+import "C"
+
+//
+// Config
+//
+
+// Zero values mean Python's defaults
+type Config struct {
+	// Used to compute paths, defaults to "python3"
+	ProgramName string
+
+	// Equivalent to PYTHONHOME
+	Home string
+
+	// If not nil, replaces the computed sys.path entirely
+	ModuleSearchPaths []string
+
+	// Ignore environment variables and user site-packages, like "python -I"
+	Isolated bool
+
+	// Like "python -X", e.g. "dev" or "importtime"
+	XOptions []string
+
+	// Like "python -X utf8"
+	UTF8Mode bool
+
+	// Becomes sys.argv as is (it is not parsed for Python command line options)
+	Argv []string
+
+	// Don't import the site module, like "python -S"
+	SkipSiteImport bool
+
+	// Don't write .pyc files, like "python -B"
+	DontWriteBytecode bool
+
+	// If not nil, sets the hash seed, like PYTHONHASHSEED
+	HashSeed *uint64
+
+	// Path to the Python executable, used to compute paths. Overrides UseVirtualEnv.
+	Executable string
+
+	// Path to a virtual environment to use instead of the one set with UseVirtualEnv (if any).
+	// Overrides Executable.
+	VirtualEnv string
+}
+
+// Like Initialize but configured from Go. Returns an *InitializationError if Python could not be
+// initialized, instead of aborting the process.
+func InitializeWithConfig(config Config) error {
+	if C.Py_IsInitialized() != 0 {
+		return errors.New("Python is already initialized")
+	}
+
+	// Preinitialization
+
+	var preConfig C.PyPreConfig
+	if config.Isolated {
+		C.PyPreConfig_InitIsolatedConfig(&preConfig)
+	} else {
+		C.PyPreConfig_InitPythonConfig(&preConfig)
+	}
+	if config.UTF8Mode {
+		preConfig.utf8_mode = 1
+	}
+	if config.devMode() {
+		preConfig.dev_mode = 1
+	}
+	if err := newInitializationError(C.Py_PreInitialize(&preConfig)); err != nil {
+		return err
+	}
+
+	// Initialization
+
+	config_ := (*C.PyConfig)(C.malloc(C.sizeof_PyConfig))
+	defer C.free(unsafe.Pointer(config_))
+
+	if config.Isolated {
+		C.PyConfig_InitIsolatedConfig(config_)
+	} else {
+		C.PyConfig_InitPythonConfig(config_)
+	}
+	defer C.PyConfig_Clear(config_)
+
+	if err := config.apply(config_); err != nil {
+		return err
+	}
+
+	return newInitializationError(C.Py_InitializeFromConfig(config_))
+}
+
+func (self *Config) apply(config *C.PyConfig) error {
+	executable := self.Executable
+	if self.VirtualEnv != "" {
+		if virtualEnv, err := LoadVirtualEnv(self.VirtualEnv); err == nil {
+			executable = virtualEnv.Executable()
+		} else {
+			return err
+		}
+	} else if virtualEnv := getDefaultVirtualEnv(); (virtualEnv != nil) && (executable == "") {
+		executable = virtualEnv.Executable()
+	}
+
+	if executable != "" {
+		if err := setConfigString(config, &config.executable, executable); err != nil {
+			return err
+		}
+	}
+
+	if self.ProgramName != "" {
+		if err := setConfigString(config, &config.program_name, self.ProgramName); err != nil {
+			return err
+		}
+	}
+
+	if self.Home != "" {
+		if err := setConfigString(config, &config.home, self.Home); err != nil {
+			return err
+		}
+	}
+
+	if self.ModuleSearchPaths != nil {
+		config.module_search_paths_set = 1
+		if err := appendConfigStrings(&config.module_search_paths, self.ModuleSearchPaths); err != nil {
+			return err
+		}
+	}
+
+	if err := appendConfigStrings(&config.xoptions, self.XOptions); err != nil {
+		return err
+	}
+	if self.devMode() {
+		config.dev_mode = 1
+	}
+
+	if self.Argv != nil {
+		config.parse_argv = 0
+
+		argv := make([]*C.char, len(self.Argv))
+		for index, arg := range self.Argv {
+			argv[index] = C.CString(arg)
+			defer C.free(unsafe.Pointer(argv[index]))
+		}
+
+		var argv_ **C.char
+		if len(argv) > 0 {
+			argv_ = &argv[0]
+		}
+
+		if err := newInitializationError(C.PyConfig_SetBytesArgv(config, C.Py_ssize_t(len(argv)), argv_)); err != nil {
+			return err
+		}
+	}
+
+	if self.SkipSiteImport {
+		config.site_import = 0
+	}
+
+	if self.DontWriteBytecode {
+		config.write_bytecode = 0
+	}
+
+	if self.HashSeed != nil {
+		config.use_hash_seed = 1
+		config.hash_seed = C.ulong(*self.HashSeed)
+	}
+
+	return nil
+}
+
+// "-X dev" must also be set explicitly
+func (self *Config) devMode() bool {
+	for _, option := range self.XOptions {
+		if option == "dev" {
+			return true
+		}
+	}
+	return false
+}
+
+func setConfigString(config *C.PyConfig, field **C.wchar_t, value string) error {
+	value_ := C.CString(value)
+	defer C.free(unsafe.Pointer(value_))
+
+	return newInitializationError(C.PyConfig_SetBytesString(config, field, value_))
+}
+
+func appendConfigStrings(list *C.PyWideStringList, values []string) error {
+	for _, value := range values {
+		value_ := C.CString(value)
+		err := newInitializationError(C.py4go_appendWideString(list, value_))
+		C.free(unsafe.Pointer(value_))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// InitializationError
+//
+
+type InitializationError struct {
+	Function string // the C function that failed, if known
+	Message  string
+
+	// If IsExit is true then Python requested to exit the process with ExitCode, e.g. for
+	// "--help" or "--version"
+	IsExit   bool
+	ExitCode int
+}
+
+// Returns nil if the status is not an exception
+func newInitializationError(status C.PyStatus) error {
+	if C.PyStatus_Exception(status) == 0 {
+		return nil
+	}
+
+	self := InitializationError{
+		IsExit:   C.PyStatus_IsExit(status) != 0,
+		ExitCode: int(status.exitcode),
+	}
+	if status._func != nil {
+		self.Function = C.GoString(status._func)
+	}
+	if status.err_msg != nil {
+		self.Message = C.GoString(status.err_msg)
+	}
+	return &self
+}
+
+// error signature
+func (self *InitializationError) Error() string {
+	if self.IsExit {
+		return fmt.Sprintf("Python initialization exited with code %d", self.ExitCode)
+	} else if self.Function != "" {
+		return fmt.Sprintf("Python initialization failed: %s: %s", self.Function, self.Message)
+	} else {
+		return fmt.Sprintf("Python initialization failed: %s", self.Message)
+	}
+}
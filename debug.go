@@ -0,0 +1,7 @@
+//go:build py4go_debug
+// +build py4go_debug
+
+package python
+
+// Build with "-tags py4go_debug" to panic on reference misuse, such as double releases
+const debug = true
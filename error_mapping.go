@@ -0,0 +1,210 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/exceptions.html#exception-classes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+var errorMappings []errorMapping
+var errorMappingsLock sync.RWMutex
+
+type errorMapping struct {
+	target     error        // for sentinels
+	targetType reflect.Type // for error types
+	type_      *Reference
+}
+
+func init() {
+	RegisterErrorMapping(os.ErrNotExist, ExcFileNotFoundError)
+	RegisterErrorMapping(os.ErrExist, ExcFileExistsError)
+	RegisterErrorMapping(os.ErrPermission, ExcPermissionError)
+	RegisterErrorMapping(os.ErrDeadlineExceeded, ExcTimeoutError)
+	RegisterErrorMapping(context.DeadlineExceeded, ExcTimeoutError)
+	RegisterErrorMapping(io.EOF, ExcEOFError)
+	RegisterErrorMapping(io.ErrUnexpectedEOF, ExcEOFError)
+	RegisterErrorMapping(strconv.ErrSyntax, ExcValueError)
+	RegisterErrorMapping(strconv.ErrRange, ExcOverflowError)
+}
+
+// When RaiseGoError is called with an error that matches the target via errors.Is, it will raise
+// an exception of the type with the error message.
+//
+// Later registrations take precedence, so you can override the defaults. E.g. os.ErrNotExist is
+// mapped to FileNotFoundError by default.
+//
+// Panics if target or type_ is nil.
+func RegisterErrorMapping(target error, type_ *Reference) {
+	if target == nil {
+		panic("python: nil error mapping target")
+	}
+	if type_ == nil {
+		panic("python: nil error mapping type")
+	}
+
+	errorMappingsLock.Lock()
+	defer errorMappingsLock.Unlock()
+
+	errorMappings = append(errorMappings, errorMapping{target: target, type_: type_})
+}
+
+// When RaiseGoError is called with an error that matches the prototype's type via errors.As, it
+// will raise an exception of the type with the error message. If the error is a struct (or
+// pointer to struct) its exported fields will be set as attributes of the exception, named by
+// "py" tag or else in snake case (see NewReferenceFromValue for the conversion rules).
+//
+// Later registrations take precedence. Panics if prototype or type_ is nil.
+func RegisterErrorTypeMapping(prototype error, type_ *Reference) {
+	if prototype == nil {
+		panic("python: nil error mapping prototype")
+	}
+	if type_ == nil {
+		panic("python: nil error mapping type")
+	}
+
+	errorMappingsLock.Lock()
+	defer errorMappingsLock.Unlock()
+
+	errorMappings = append(errorMappings, errorMapping{targetType: reflect.TypeOf(prototype), type_: type_})
+}
+
+// Creates a new exception class. The name must be qualified with a module name, e.g.
+// "api.QuotaError". If base is nil the class will inherit from Exception.
+func NewExceptionType(name string, base *Reference) (*Reference, error) {
+	name_ := C.CString(name)
+	defer C.free(unsafe.Pointer(name_))
+
+	var base_ *C.PyObject
+	if base != nil {
+		base_ = base.Object
+	}
+
+	if type_ := C.PyErr_NewException(name_, base_, nil); type_ != nil {
+		return NewReference(type_), nil
+	} else {
+		return nil, GetError()
+	}
+}
+
+// Adds a new exception class to the module, qualifying its name with the module name
+func (self *Reference) AddModuleExceptionType(name string, base *Reference) (*Reference, error) {
+	if moduleName, err := self.GetModuleName(); err == nil {
+		if type_, err := NewExceptionType(moduleName+"."+name, base); err == nil {
+			if err := self.SetAttr(name, type_); err == nil {
+				return type_, nil
+			} else {
+				type_.Release()
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Sets the current exception to a new instance of the type, created with the message as its
+// argument and then with the attributes set on it
+//
+// Use this in Go code called from Python, which should then return an error indicator to Python.
+func RaiseException(type_ *Reference, message string, attributes map[string]interface{}) {
+	if exception, err := type_.Call(message); err == nil {
+		defer exception.Release()
+
+		for name, value := range attributes {
+			if value_, err := NewReferenceFromValue(value); err == nil {
+				err = exception.SetAttr(name, value_)
+				value_.Release()
+				if err != nil {
+					RaiseGoError(err)
+					return
+				}
+			} else {
+				RaiseGoError(err)
+				return
+			}
+		}
+
+		C.PyErr_SetObject(type_.Object, exception.Object)
+	} else {
+		RaiseGoError(err)
+	}
+}
+
+// Returns false if there is no mapping
+func raiseMappedError(err error) bool {
+	// Raising runs Python code, which may in turn register mappings, so we must not hold the lock
+	errorMappingsLock.RLock()
+	mappings := make([]errorMapping, len(errorMappings))
+	copy(mappings, errorMappings)
+	errorMappingsLock.RUnlock()
+
+	for index := len(mappings) - 1; index >= 0; index-- {
+		mapping := mappings[index]
+
+		if mapping.target != nil {
+			if errors.Is(err, mapping.target) {
+				RaiseException(mapping.type_, err.Error(), nil)
+				return true
+			}
+		} else {
+			target := reflect.New(mapping.targetType)
+			if errors.As(err, target.Interface()) {
+				RaiseException(mapping.type_, err.Error(), getErrorAttributes(target.Elem()))
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func getErrorAttributes(value reflect.Value) map[string]interface{} {
+	for (value.Kind() == reflect.Ptr) || (value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	attributes := make(map[string]interface{})
+	type_ := value.Type()
+	for index := 0; index < type_.NumField(); index++ {
+		field := type_.Field(index)
+		if field.PkgPath != "" {
+			// Unexported
+			continue
+		}
+
+		if name, omitEmpty, skip := parseFieldTag(field); !skip {
+			fieldValue := value.Field(index)
+			if omitEmpty && isEmptyValue(fieldValue) {
+				continue
+			}
+			if field.Tag.Get(TAG) == "" {
+				name = toSnakeCase(name)
+			}
+			attributes[name] = fieldValue.Interface()
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,173 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"testing"
+)
+
+type testQuotaError struct {
+	Limit     int
+	Remaining int    `py:"left"`
+	Internal  string `py:"-"`
+}
+
+func (self *testQuotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", self.Limit)
+}
+
+// Restores the registered error mappings when the test is done
+func scopeErrorMappings(t *testing.T) {
+	errorMappingsLock.RLock()
+	errorMappings_ := append([]errorMapping(nil), errorMappings...)
+	errorMappingsLock.RUnlock()
+
+	t.Cleanup(func() {
+		errorMappingsLock.Lock()
+		defer errorMappingsLock.Unlock()
+
+		errorMappings = errorMappings_
+	})
+}
+
+func TestErrorMappingDefaults(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		_, strconvErr := strconv.Atoi("x")
+
+		for _, test := range []struct {
+			err    error
+			target *Reference
+		}{
+			{&fs.PathError{Op: "open", Path: "missing", Err: fs.ErrNotExist}, ExcFileNotFoundError},
+			{fmt.Errorf("wrapped: %w", os.ErrPermission), ExcPermissionError},
+			{context.DeadlineExceeded, ExcTimeoutError},
+			{strconvErr, ExcValueError},
+		} {
+			RaiseGoError(test.err)
+
+			if exception := FetchException(); exception != nil {
+				if !exception.Matches(test.target) {
+					t.Errorf("%v: expected %s, got %s", test.err, test.target.String(), exception.TypeName)
+				}
+				if exception.Error() != test.err.Error() {
+					t.Errorf("%v: unexpected message %q", test.err, exception.Error())
+				}
+				exception.Release()
+			} else {
+				t.Errorf("%v: expected an exception", test.err)
+			}
+		}
+	})
+}
+
+func TestErrorMapping(t *testing.T) {
+	CheckLeaks(t)
+	scopeErrorMappings(t)
+
+	withGIL(t, func() {
+		type_, err := NewExceptionType("test.MappedError", ExcLookupError)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer type_.Release()
+
+		target := errors.New("mapped")
+		RegisterErrorMapping(target, type_)
+
+		RaiseGoError(fmt.Errorf("wrapped: %w", target))
+		if exception := FetchException(); (exception == nil) || !exception.Matches(type_) || !exception.Matches(ExcLookupError) {
+			t.Errorf("expected test.MappedError, got %v", exception)
+		} else {
+			if exception.TypeName != "test.MappedError" {
+				t.Errorf("unexpected name %s", exception.TypeName)
+			}
+			exception.Release()
+		}
+	})
+}
+
+func TestErrorTypeMapping(t *testing.T) {
+	CheckLeaks(t)
+	scopeErrorMappings(t)
+
+	withGIL(t, func() {
+		type_, err := NewExceptionType("test.QuotaError", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer type_.Release()
+
+		RegisterErrorTypeMapping((*testQuotaError)(nil), type_)
+
+		RaiseGoError(fmt.Errorf("wrapped: %w", &testQuotaError{Limit: 10, Remaining: 2, Internal: "x"}))
+		if exception := FetchException(); (exception != nil) && exception.Matches(type_) {
+			assertPython(t, exception.Value, "str(value) == 'wrapped: quota of 10 exceeded'")
+			assertPython(t, exception.Value, "value.limit == 10 and value.left == 2 and not hasattr(value, 'internal')")
+			exception.Release()
+		} else {
+			t.Errorf("expected test.QuotaError, got %v", exception)
+		}
+
+		// Go code called from Python
+		function := newTestGoFunction(t, "use", func() error {
+			return &testQuotaError{Limit: 5}
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "f()"); err == nil {
+			result.Release()
+			t.Error("expected an error")
+		} else {
+			var exception *Exception
+			if errors.As(err, &exception) && exception.Matches(type_) {
+				assertPython(t, exception.Value, "value.limit == 5")
+			} else {
+				t.Errorf("expected test.QuotaError, got %v", err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+func TestRegisterErrorMappingNil(t *testing.T) {
+	scopeErrorMappings(t)
+
+	for name, register := range map[string]func(){
+		"target":    func() { RegisterErrorMapping(nil, ExcValueError) },
+		"type":      func() { RegisterErrorMapping(errors.New("x"), nil) },
+		"prototype": func() { RegisterErrorTypeMapping(nil, ExcValueError) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestScopeErrorMappings(t *testing.T) {
+	errorMappingsLock.RLock()
+	count := len(errorMappings)
+	errorMappingsLock.RUnlock()
+
+	t.Run("register", func(t *testing.T) {
+		scopeErrorMappings(t)
+		RegisterErrorMapping(errors.New("scoped"), ExcValueError)
+	})
+
+	errorMappingsLock.RLock()
+	defer errorMappingsLock.RUnlock()
+
+	if len(errorMappings) != count {
+		t.Errorf("expected %d mappings, got %d", count, len(errorMappings))
+	}
+}
@@ -0,0 +1,195 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/veryhigh.html
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+const SOURCE_FILENAME = "<string>"
+
+var builtinsName = C.CString("__builtins__")
+
+//
+// CompileMode
+//
+
+type CompileMode int
+
+const (
+	// A sequence of statements, like a module
+	CompileExec CompileMode = C.Py_file_input
+
+	// A single expression
+	CompileEval CompileMode = C.Py_eval_input
+
+	// A single interactive statement, like in the REPL
+	CompileSingle CompileMode = C.Py_single_input
+)
+
+// Compiles the source into a code object. If the source is invalid the error is a *SyntaxError.
+func CompileSource(source string, filename string, mode CompileMode) (*Reference, error) {
+	source_ := C.CString(source)
+	defer C.free(unsafe.Pointer(source_))
+
+	filename_ := C.CString(filename)
+	defer C.free(unsafe.Pointer(filename_))
+
+	if code := C.Py_CompileStringExFlags(source_, filename_, C.int(mode), nil, -1); code != nil {
+		return NewReference(code), nil
+	} else {
+		return nil, getCompileError()
+	}
+}
+
+// Runs a code object (see CompileSource). If globals is nil a new dict is used. If locals is nil
+// globals is used. Like Python's exec(), if globals does not have "__builtins__" then the current
+// builtins are added to it.
+func EvalCode(code *Reference, globals *Reference, locals *Reference) (*Reference, error) {
+	if globals == nil {
+		if globals_, err := NewDict(); err == nil {
+			defer globals_.Release()
+			globals = globals_
+		} else {
+			return nil, err
+		}
+	}
+
+	if locals == nil {
+		locals = globals
+	}
+
+	if err := checkInterpreters(code, globals, locals); err != nil {
+		return nil, err
+	}
+
+	// Otherwise imports from Go code called by the code would fail
+	if C.PyDict_GetItemString(globals.Object, builtinsName) == nil {
+		if C.PyDict_SetItemString(globals.Object, builtinsName, C.PyEval_GetBuiltins()) != 0 {
+			return nil, GetError()
+		}
+	}
+
+	if r := C.PyEval_EvalCode(code.Object, globals.Object, locals.Object); r != nil {
+		return NewReference(r), nil
+	} else {
+		return nil, GetError()
+	}
+}
+
+// Executes statements, like Python's exec(). If globals is nil a new dict is used. If locals is
+// nil globals is used. If the source is invalid the error is a *SyntaxError.
+func Exec(source string, globals *Reference, locals *Reference) error {
+	if code, err := CompileSource(source, SOURCE_FILENAME, CompileExec); err == nil {
+		defer code.Release()
+
+		if r, err := EvalCode(code, globals, locals); err == nil {
+			r.Release()
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Evaluates an expression, like Python's eval(). If globals is nil a new dict is used. If locals
+// is nil globals is used. If the expression is invalid the error is a *SyntaxError.
+func Eval(expression string, globals *Reference, locals *Reference) (*Reference, error) {
+	if code, err := CompileSource(expression, SOURCE_FILENAME, CompileEval); err == nil {
+		defer code.Release()
+		return EvalCode(code, globals, locals)
+	} else {
+		return nil, err
+	}
+}
+
+//
+// SyntaxError
+//
+
+type SyntaxError struct {
+	Message   string
+	Filename  string
+	Line      int    // 1-based, 0 if unknown
+	Column    int    // 1-based, 0 if unknown
+	EndLine   int    // 0 if unknown
+	EndColumn int    // 0 if unknown
+	Text      string // the offending line of source, if available
+
+	// The Python exception (a SyntaxError or subclass, e.g. IndentationError)
+	Exception *Exception
+}
+
+// error signature
+func (self *SyntaxError) Error() string {
+	if self.Line > 0 {
+		if self.Column > 0 {
+			return fmt.Sprintf("%s:%d:%d: %s", self.Filename, self.Line, self.Column, self.Message)
+		} else {
+			return fmt.Sprintf("%s:%d: %s", self.Filename, self.Line, self.Message)
+		}
+	} else {
+		return fmt.Sprintf("%s: %s", self.Filename, self.Message)
+	}
+}
+
+// errors.Unwrap signature
+func (self *SyntaxError) Unwrap() error {
+	return self.Exception
+}
+
+// Fetches the current exception, converting a Python SyntaxError to a *SyntaxError
+func getCompileError() error {
+	exception := FetchException()
+	if exception == nil {
+		return GetError()
+	}
+
+	if !exception.Matches(ExcSyntaxError) || (exception.Value == nil) {
+		return exception
+	}
+
+	value := exception.Value.Object
+	return &SyntaxError{
+		Message:   getStringAttrRaw(value, "msg"),
+		Filename:  getStringAttrRaw(value, "filename"),
+		Line:      getIntAttrRaw(value, "lineno"),
+		Column:    getIntAttrRaw(value, "offset"),
+		EndLine:   getIntAttrRaw(value, "end_lineno"),
+		EndColumn: getIntAttrRaw(value, "end_offset"),
+		Text:      trimSourceText(getStringAttrRaw(value, "text")),
+		Exception: exception,
+	}
+}
+
+// Returns 0 on failure, without leaving an exception
+func getIntAttrRaw(object *C.PyObject, name string) int {
+	if attr := getAttrRaw(object, name); attr != nil {
+		defer C.Py_DecRef(attr)
+
+		if attr_ := NewBorrowedReference(attr); attr_.IsLong() {
+			if int_, err := attr_.ToInt64(); err == nil {
+				return int(int_)
+			}
+		}
+	}
+	C.PyErr_Clear()
+	return 0
+}
+
+func trimSourceText(text string) string {
+	for (len(text) > 0) && ((text[len(text)-1] == '\n') || (text[len(text)-1] == '\r')) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
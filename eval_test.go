@@ -0,0 +1,143 @@
+package python
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecAndEval(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newGlobals(t, map[string]interface{}{"base": 10})
+		defer globals.Release()
+
+		if err := Exec("def add(value):\n    return base + value\n", globals, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if result, err := Eval("add(5)", globals, nil); err == nil {
+			assertPython(t, result, "value == 15")
+			result.Release()
+		} else {
+			t.Fatal(err)
+		}
+
+		// Like exec(), the builtins are added to the globals
+		assertPython(t, globals, "'__builtins__' in value")
+	})
+}
+
+func TestEvalCode(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		code, err := CompileSource("value * 2", "test.py", CompileEval)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer code.Release()
+
+		for value, expression := range map[int]string{1: "value == 2", 21: "value == 42"} {
+			globals := newGlobals(t, map[string]interface{}{"value": value})
+			if result, err := EvalCode(code, globals, nil); err == nil {
+				assertPython(t, result, expression)
+				result.Release()
+			} else {
+				t.Error(err)
+			}
+			globals.Release()
+		}
+	})
+}
+
+func TestSyntaxError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, test := range []struct {
+			source   string
+			target   error
+			expected SyntaxError
+		}{
+			{"a = 1\nb = = 2\n", ErrSyntaxError, SyntaxError{
+				Message:   "invalid syntax",
+				Filename:  "test.py",
+				Line:      2,
+				Column:    5,
+				EndLine:   2,
+				EndColumn: 6,
+				Text:      "b = = 2",
+			}},
+			{"if True:\nprint(1)\n", ErrIndentationError, SyntaxError{
+				// The message differs between Python versions
+				Filename: "test.py",
+				Line:     2,
+				Column:   1,
+				Text:     "print(1)",
+			}},
+		} {
+			code, err := CompileSource(test.source, "test.py", CompileExec)
+			if err == nil {
+				code.Release()
+				t.Errorf("%q: expected an error", test.source)
+				continue
+			}
+
+			var syntaxError *SyntaxError
+			if !errors.As(err, &syntaxError) {
+				t.Errorf("%q: expected a *SyntaxError, got %v", test.source, err)
+				releaseError(err)
+				continue
+			}
+
+			if !errors.Is(err, test.target) {
+				t.Errorf("%q: expected %s", test.source, test.target)
+			}
+
+			expected := test.expected
+			expected.Exception = syntaxError.Exception
+			if expected.Message == "" {
+				expected.Message = syntaxError.Message
+				expected.EndLine = syntaxError.EndLine
+				expected.EndColumn = syntaxError.EndColumn
+			}
+			if *syntaxError != expected {
+				t.Errorf("%q: expected %#v, got %#v", test.source, expected, *syntaxError)
+			}
+
+			if !strings.HasPrefix(err.Error(), "test.py:2:") {
+				t.Errorf("%q: unexpected error %q", test.source, err.Error())
+			}
+
+			syntaxError.Exception.Release()
+		}
+
+		// Exec and Eval use the default filename
+		if err := Exec("def", nil, nil); err == nil {
+			t.Error("expected an error")
+		} else {
+			var syntaxError *SyntaxError
+			if !errors.As(err, &syntaxError) || (syntaxError.Filename != SOURCE_FILENAME) {
+				t.Errorf("expected a *SyntaxError for %s, got %v", SOURCE_FILENAME, err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+func TestEvalRuntimeError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		// Not a *SyntaxError
+		_, err := Eval("1 / 0", nil, nil)
+		defer releaseError(err)
+
+		var syntaxError *SyntaxError
+		if errors.As(err, &syntaxError) || !errors.Is(err, ErrZeroDivisionError) {
+			t.Errorf("expected ZeroDivisionError, got %v", err)
+		}
+	})
+}
@@ -2,15 +2,24 @@ package api
 
 // Here we define our functions in plain Go
 
+// The cgo wrappers that expose them to Python are generated by py4go-gen:
+//go:generate go run github.com/tliron/py4go/py4go-gen -module api
+
 import (
 	"fmt"
 )
 
-func sayGoodbye() {
+func SayGoodbye() {
 	fmt.Println("Go >> Goodbye from Go!")
 }
 
-func concat(a string, b string) string {
+func Concat(a string, b string) string {
 	fmt.Printf("Go >> Concatenating %q and %q\n", a, b)
 	return a + " " + b
 }
+
+// Like Concat (the generated wrappers use the _PyCFunctionFast ABI for both)
+func ConcatFast(a string, b string) string {
+	fmt.Printf("Go >> Concatenating %q and %q (fast)\n", a, b)
+	return a + " " + b
+}
@@ -0,0 +1,53 @@
+// Code generated by py4go-gen. DO NOT EDIT.
+
+package api
+
+// Here we export cgo wrappers for our plain Go functions
+// They handle the conversion between Go and C types
+
+import (
+	"fmt"
+
+	python "github.com/tliron/py4go"
+)
+
+/*
+#cgo pkg-config: python3-embed
+
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+//export go_api_Concat
+func go_api_Concat(arg0 *C.char, arg1 *C.char, r **C.char) (status C.int) {
+	defer py4goRecover(&status)
+
+	r_ := Concat(C.GoString(arg0), C.GoString(arg1))
+	*r = C.CString(r_)
+	return 0
+}
+
+//export go_api_ConcatFast
+func go_api_ConcatFast(arg0 *C.char, arg1 *C.char, r **C.char) (status C.int) {
+	defer py4goRecover(&status)
+
+	r_ := ConcatFast(C.GoString(arg0), C.GoString(arg1))
+	*r = C.CString(r_)
+	return 0
+}
+
+//export go_api_SayGoodbye
+func go_api_SayGoodbye() (status C.int) {
+	defer py4goRecover(&status)
+
+	SayGoodbye()
+	return 0
+}
+
+func py4goRecover(status *C.int) {
+	if recovered := recover(); recovered != nil {
+		python.RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+		*status = -1
+	}
+}
@@ -0,0 +1,107 @@
+// Code generated by py4go-gen. DO NOT EDIT.
+
+package api
+
+// Here we define Python wrappers in C for our exported "go_" functions and add them to the module
+// They handle the conversion between Python and C types using the _PyCFunctionFast ABI
+
+import (
+	python "github.com/tliron/py4go"
+)
+
+/*
+#cgo pkg-config: python3-embed
+
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+#include <stdint.h>
+
+int go_api_Concat(char *arg0, char *arg1, char **r);
+int go_api_ConcatFast(char *arg0, char *arg1, char **r);
+int go_api_SayGoodbye(void);
+
+// _PyCFunctionFast signature
+PyObject *py_api_concat(PyObject *self, PyObject *const *args, Py_ssize_t nargs) {
+	if (nargs != 2) {
+		PyErr_Format(PyExc_TypeError, "concat() takes 2 arguments (%zd given)", nargs);
+		return NULL;
+	}
+
+	char *arg0 = (char *) PyUnicode_AsUTF8(args[0]);
+	if (arg0 == NULL)
+		return NULL;
+
+	char *arg1 = (char *) PyUnicode_AsUTF8(args[1]);
+	if (arg1 == NULL)
+		return NULL;
+
+	char *r = NULL;
+	if (go_api_Concat(arg0, arg1, &r) != 0)
+		return NULL;
+
+	PyObject *r_ = PyUnicode_FromString(r);
+	free(r);
+	return r_;
+}
+
+// _PyCFunctionFast signature
+PyObject *py_api_concat_fast(PyObject *self, PyObject *const *args, Py_ssize_t nargs) {
+	if (nargs != 2) {
+		PyErr_Format(PyExc_TypeError, "concat_fast() takes 2 arguments (%zd given)", nargs);
+		return NULL;
+	}
+
+	char *arg0 = (char *) PyUnicode_AsUTF8(args[0]);
+	if (arg0 == NULL)
+		return NULL;
+
+	char *arg1 = (char *) PyUnicode_AsUTF8(args[1]);
+	if (arg1 == NULL)
+		return NULL;
+
+	char *r = NULL;
+	if (go_api_ConcatFast(arg0, arg1, &r) != 0)
+		return NULL;
+
+	PyObject *r_ = PyUnicode_FromString(r);
+	free(r);
+	return r_;
+}
+
+// _PyCFunctionFast signature
+PyObject *py_api_say_goodbye(PyObject *self, PyObject *const *args, Py_ssize_t nargs) {
+	if (nargs != 0) {
+		PyErr_Format(PyExc_TypeError, "say_goodbye() takes 0 arguments (%zd given)", nargs);
+		return NULL;
+	}
+
+	if (go_api_SayGoodbye() != 0)
+		return NULL;
+
+	Py_RETURN_NONE;
+}
+*/
+import "C"
+
+func CreateModule() (*python.Reference, error) {
+	if module, err := python.CreateModule("api"); err == nil {
+		if err := module.AddModuleCFunctionFastArgs("concat", C.py_api_concat); err != nil {
+			module.Release()
+			return nil, err
+		}
+
+		if err := module.AddModuleCFunctionFastArgs("concat_fast", C.py_api_concat_fast); err != nil {
+			module.Release()
+			return nil, err
+		}
+
+		if err := module.AddModuleCFunctionFastArgs("say_goodbye", C.py_api_say_goodbye); err != nil {
+			module.Release()
+			return nil, err
+		}
+
+		return module, nil
+	} else {
+		return nil, err
+	}
+}
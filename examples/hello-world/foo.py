@@ -17,6 +17,9 @@ def say_name():
 def say_name_fast():
     print("Python >> The name is " + api.concat_fast("Tal", "Liron"))
 
+def add_numbers():
+    print("Python >> The sum is " + str(api.add(1, 2)))
+
 size = 0
 
 def grow(count):
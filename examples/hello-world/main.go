@@ -1,13 +1,20 @@
 package main
 
 import (
+	"embed"
 	"fmt"
+	"os"
 	"sync"
 
 	python "github.com/tliron/py4go"
 	"github.com/tliron/py4go/examples/hello-world/api"
 )
 
+// Our Python code is embedded in the binary
+//
+//go:embed foo.py
+var pythonFS embed.FS
+
 func version() {
 	fmt.Printf("Go >> Python version:\n%s\n", python.Version())
 }
@@ -21,11 +28,21 @@ func typeChecking() {
 func callPythonFunction(module *python.Reference) {
 	fmt.Println("Go >> Calling a Python function:")
 
-	hello, _ := module.GetAttr("hello")
-	defer hello.Release()
+	// A scope releases all its references when closed, even on error paths
+	scope := python.NewScope()
+	defer scope.Close()
 
-	r, _ := hello.Call("Tal")
-	defer r.Release()
+	hello, err := scope.Track(module.GetAttr("hello"))
+	if err != nil {
+		fmt.Printf("Go >> Error: %s\n", err)
+		return
+	}
+
+	r, err := scope.Track(hello.Call("Tal"))
+	if err != nil {
+		fmt.Printf("Go >> Error: %s\n", err)
+		return
+	}
 
 	r_, _ := r.ToString()
 	fmt.Printf("Go >> Python function returned: %s\n", r_)
@@ -34,13 +51,14 @@ func callPythonFunction(module *python.Reference) {
 func callPythonMethod(module *python.Reference) {
 	fmt.Println("Go >> Calling a Python method:")
 
-	person, _ := module.GetAttr("person")
-	defer person.Release()
-
-	greet, _ := person.GetAttr("greet")
-	defer greet.Release()
+	scope := python.NewScope()
+	defer scope.Close()
 
-	greet.Call()
+	if person, err := scope.Track(module.GetAttr("person")); err == nil {
+		if greet, err := scope.Track(person.GetAttr("greet")); err == nil {
+			scope.Track(greet.Call())
+		}
+	}
 }
 
 func getPythonException(module *python.Reference) {
@@ -51,6 +69,15 @@ func getPythonException(module *python.Reference) {
 
 	if _, err := bad.Call(); err != nil {
 		fmt.Printf("Go >> Error message: %s\n", err)
+
+		if exception, ok := err.(*python.Exception); ok {
+			defer exception.Release()
+
+			fmt.Printf("Go >> Exception type: %s\n", exception.TypeName)
+			for _, frame := range exception.Frames() {
+				fmt.Printf("Go >> Traceback: %s: %s\n", frame, frame.Source)
+			}
+		}
 	}
 }
 
@@ -66,6 +93,10 @@ func callGoFromPython(module *python.Reference) {
 	sayNameFast, _ := module.GetAttr("say_name_fast")
 	defer sayNameFast.Release()
 	sayNameFast.Call()
+
+	addNumbers, _ := module.GetAttr("add_numbers")
+	defer addNumbers.Release()
+	addNumbers.Call()
 }
 
 func concurrency(module *python.Reference) {
@@ -91,18 +122,23 @@ func concurrency(module *python.Reference) {
 			go func() {
 				defer waitGroup.Done()
 
-				for i := 0; i < 100; i++ {
-					// We must manually acquire Python's Global Interpreter Lock (GIL),
-					// because Python doesn't know about our Go "threads" (goroutines)
-					gs := python.EnsureGilState()
-					defer gs.Release()
-
-					// (Note: We could also have acquired the GIL outside of this for-loop;
-					// it's up to how we want to balance concurrency with the cost of context
-					// switching)
-
-					grow.Call(1)
-				}
+				// We must acquire Python's Global Interpreter Lock (GIL), because Python doesn't
+				// know about our Go "threads" (goroutines). WithGIL also locks the goroutine to
+				// its OS thread, because the GIL state belongs to the thread.
+				python.WithGIL(func() error {
+					// (Note: We could also have acquired the GIL inside the for-loop; it's up to
+					// how we want to balance concurrency with the cost of context switching)
+
+					for i := 0; i < 100; i++ {
+						if r, err := grow.Call(1); err == nil {
+							r.Release()
+						} else {
+							return err
+						}
+					}
+
+					return nil
+				})
 			}()
 		}
 	}()
@@ -115,11 +151,17 @@ func concurrency(module *python.Reference) {
 }
 
 func main() {
-	python.PrependPythonPath(".")
-
 	python.Initialize()
 	defer python.Finalize()
 
+	// Import Python modules from our embedded files
+	python.AddFSImporter("embed", pythonFS)
+
+	// Send Python's output through Go, so that it's ordered with our own output even when
+	// Python would otherwise buffer it
+	stdout, _ := python.RedirectStdout(os.Stdout)
+	defer stdout.Restore()
+
 	version()
 	fmt.Println()
 
@@ -128,6 +170,13 @@ func main() {
 
 	api, _ := api.CreateModule()
 	defer api.Release()
+
+	// Go functions can also be added directly, without C wrappers
+	api.AddModuleGoFunction("add", func(a int, b int) int {
+		fmt.Printf("Go >> Adding %d and %d\n", a, b)
+		return a + b
+	})
+
 	api.EnableModule()
 
 	foo, _ := python.Import("foo")
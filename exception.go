@@ -2,9 +2,13 @@ package python
 
 // See:
 //   https://docs.python.org/3/c-api/exceptions.html
+//   https://docs.python.org/3/library/traceback.html
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
 )
 
 /*
@@ -13,10 +17,14 @@ import (
 */
 import "C"
 
+// Limits the depth of __cause__ and __context__ chains
+const MAX_EXCEPTION_CHAIN = 100
+
 func HasException() bool {
 	return C.PyErr_Occurred() != nil
 }
 
+// Fetches and clears the current exception
 func GetError() error {
 	if exception := FetchException(); exception != nil {
 		return exception
@@ -25,6 +33,70 @@ func GetError() error {
 	}
 }
 
+func ClearError() {
+	C.PyErr_Clear()
+}
+
+// Sets the current exception, e.g. RaiseError(ExcValueError, "bad value")
+//
+// Use this in Go code called from Python, which should then return an error indicator to Python.
+func RaiseError(type_ *Reference, message string) {
+	message_ := C.CString(message)
+	defer C.free(unsafe.Pointer(message_))
+
+	C.PyErr_SetString(type_.Object, message_)
+}
+
+// Sets the current exception from a Go error. An *Exception is restored as is and an
+// *ExceptionClass is raised without a message. Errors registered with RegisterErrorMapping or
+// RegisterErrorTypeMapping are raised as their mapped exception type, as are errors wrapping an
+// *Exception or an *ExceptionClass (e.g. fmt.Errorf("bad port: %w", ErrValueError)). Otherwise a
+// RuntimeError is raised with the error message.
+//
+// Use this in Go code called from Python, which should then return an error indicator to Python.
+func RaiseGoError(err error) {
+	switch err_ := err.(type) {
+	case *Exception:
+		err_.Restore()
+
+	case *ExceptionClass:
+		C.PyErr_SetNone(err_.Type.Object)
+
+	case *typeError:
+		RaiseError(ExcTypeError, err_.message)
+
+	default:
+		if raiseMappedError(err) {
+			return
+		}
+
+		var exception *Exception
+		if errors.As(err, &exception) {
+			exception.Restore()
+			return
+		}
+
+		var exceptionClass *ExceptionClass
+		if errors.As(err, &exceptionClass) {
+			RaiseError(exceptionClass.Type, err.Error())
+			return
+		}
+
+		RaiseError(ExcRuntimeError, err.Error())
+	}
+}
+
+// Like RaiseGoError, but also releases the *Exception in the error (if there is one). For errors
+// returned to us by the Go code that Python calls.
+func raiseReturnedError(err error) {
+	RaiseGoError(err)
+
+	var exception *Exception
+	if errors.As(err, &exception) {
+		exception.Release()
+	}
+}
+
 //
 // Exception
 //
@@ -33,13 +105,34 @@ type Exception struct {
 	Type      *Reference
 	Value     *Reference
 	Traceback *Reference
+
+	// Qualified name of the exception class, e.g. "KeyError" or "json.decoder.JSONDecodeError"
+	TypeName string
+
+	// Rendered when created, so that Error does not require the GIL
+	message string
+
+	// Inspected lazily, see Frames, Cause, and Context
+	frames          []TracebackFrame
+	framesInspected bool
+	cause           *Exception
+	context         *Exception
+	suppressContext bool
+	chainInspected  bool
+	depth           int
+	released        bool
 }
 
+// Fetches, normalizes, and clears the current exception. Returns nil if there is no exception.
 func FetchException() *Exception {
 	var type_, value, traceback *C.PyObject
 	C.PyErr_Fetch(&type_, &value, &traceback)
 	if type_ != nil {
-		defer C.PyErr_Restore(type_, value, traceback)
+		// We now own the references
+		C.PyErr_NormalizeException(&type_, &value, &traceback)
+		if (value != nil) && (traceback != nil) {
+			C.PyException_SetTraceback(value, traceback)
+		}
 
 		var type__, value_, traceback_ *Reference
 
@@ -61,22 +154,427 @@ func FetchException() *Exception {
 	}
 }
 
+// Like FetchException but does not clear the current exception
+func PeekException() *Exception {
+	if exception := FetchException(); exception != nil {
+		exception.Restore()
+		return exception
+	} else {
+		return nil
+	}
+}
+
+// Requires the GIL, in order to render the message
 func NewExceptionRaw(type_ *Reference, value *Reference, traceback *Reference) *Exception {
-	return &Exception{
+	self := Exception{
 		Type:      type_,
 		Value:     value,
 		Traceback: traceback,
 	}
+	self.inspect()
+	return &self
 }
 
 // error signature
+//
+// Does not require the GIL.
 func (self *Exception) Error() string {
-	// TODO: include traceback?
+	if (self.message == "") && (self.Value == nil) && (self.Type == nil) {
+		return "malformed Python exception"
+	} else {
+		return self.message
+	}
+}
+
+// Releases the type, value, and traceback references, as well as those of the inspected cause and
+// context. Error, TypeName, and the inspected frames remain available afterwards, but the
+// exception must otherwise no longer be used. Repeated calls are no-ops.
+//
+// Requires the GIL.
+func (self *Exception) Release() {
+	if self.released {
+		return
+	}
+	self.released = true
+
+	// Don't inspect the chain after the value is released
+	self.chainInspected = true
+
+	if self.Type != nil {
+		self.Type.Release()
+	}
 	if self.Value != nil {
-		return self.Value.String()
-	} else if self.Type != nil {
-		return self.Type.String()
+		self.Value.Release()
+	}
+	if self.Traceback != nil {
+		self.Traceback.Release()
+	}
+	if self.cause != nil {
+		self.cause.Release()
+	}
+	if self.context != nil {
+		self.context.Release()
+	}
+}
+
+// Sets this as the current exception (re-raising it)
+func (self *Exception) Restore() {
+	var type_, value, traceback *C.PyObject
+
+	// PyErr_Restore steals the references, so we must keep our own
+
+	if self.Type != nil {
+		type_ = self.Type.Object
+		C.Py_IncRef(type_)
+	}
+
+	if self.Value != nil {
+		value = self.Value.Object
+		C.Py_IncRef(value)
+	}
+
+	if self.Traceback != nil {
+		traceback = self.Traceback.Object
+		C.Py_IncRef(traceback)
+	}
+
+	C.PyErr_Restore(type_, value, traceback)
+}
+
+// Calls the function as if in an "except" block for the exception, so that sys.exc_info returns
+// it (e.g. for logging.Handler.handleError)
+func (self *Exception) handle(f func() error) error {
+	var type_, value, traceback *C.PyObject
+	C.PyErr_GetExcInfo(&type_, &value, &traceback)
+	// Steals the references
+	defer C.PyErr_SetExcInfo(type_, value, traceback)
+
+	// PyErr_SetExcInfo steals the references, so we must keep our own
+	var exception [3]*C.PyObject
+	for index, reference := range []*Reference{self.Type, self.Value, self.Traceback} {
+		if reference != nil {
+			exception[index] = reference.Object
+			C.Py_IncRef(reference.Object)
+		}
+	}
+	C.PyErr_SetExcInfo(exception[0], exception[1], exception[2])
+
+	return f()
+}
+
+// errors.Unwrap signature
+//
+// Follows the __cause__ (from "raise ... from ..."), or else the __context__ unless it is
+// suppressed (e.g. by "raise ... from None"), which is the same chain that Python's traceback
+// output displays.
+func (self *Exception) Unwrap() error {
+	if cause := self.Cause(); cause != nil {
+		return cause
+	} else if context := self.Context(); (context != nil) && !self.SuppressContext() {
+		return context
 	} else {
-		return "malformed Python exception"
+		return nil
+	}
+}
+
+// Traceback frames, most recent call last, like Python's own traceback output. Source lines are
+// read via linecache.
+//
+// Inspected on first call, which requires the GIL.
+func (self *Exception) Frames() []TracebackFrame {
+	if !self.framesInspected {
+		self.framesInspected = true
+		if self.Traceback != nil {
+			withoutException(func() {
+				self.frames = getTracebackFrames(self.Traceback.Object)
+			})
+		}
 	}
+	return self.frames
+}
+
+// The exception's __cause__ (from "raise ... from ..."), or nil.
+//
+// Inspected on first call, which requires the GIL.
+func (self *Exception) Cause() *Exception {
+	self.inspectChain()
+	return self.cause
+}
+
+// The exception's __context__ (the exception that was being handled when it was raised), or nil.
+//
+// Inspected on first call, which requires the GIL.
+func (self *Exception) Context() *Exception {
+	self.inspectChain()
+	return self.context
+}
+
+// True if the context should not be displayed, e.g. because of "raise ... from None".
+//
+// Inspected on first call, which requires the GIL.
+func (self *Exception) SuppressContext() bool {
+	self.inspectChain()
+	return self.suppressContext
+}
+
+// Returns the same text as Python's traceback.format_exception, including the chain of causes
+func (self *Exception) Format() (string, error) {
+	if self.Type == nil {
+		return "", errors.New("malformed Python exception")
+	}
+
+	var r string
+	var err error
+
+	withoutException(func() {
+		var traceback *Reference
+		if traceback, err = Import("traceback"); err != nil {
+			return
+		}
+		defer traceback.Release()
+
+		var formatException *Reference
+		if formatException, err = traceback.GetAttr("format_exception"); err != nil {
+			return
+		}
+		defer formatException.Release()
+
+		// Missing parts are passed as None
+		arguments := []interface{}{self.Type, nil, nil}
+		if self.Value != nil {
+			arguments[1] = self.Value
+		}
+		if self.Traceback != nil {
+			arguments[2] = self.Traceback
+		}
+
+		var lines *Reference
+		if lines, err = formatException.Call(arguments...); err != nil {
+			return
+		}
+		defer lines.Release()
+
+		var lines_ []string
+		if err = lines.Unmarshal(&lines_); err == nil {
+			r = strings.Join(lines_, "")
+		}
+	})
+
+	return r, err
+}
+
+// Fills in the fields that are cheap to derive
+func (self *Exception) inspect() {
+	withoutException(func() {
+		if self.Type != nil {
+			self.TypeName = getQualifiedName(self.Type.Object)
+		}
+
+		if self.Value != nil {
+			self.message = self.Value.String()
+		} else if self.Type != nil {
+			self.message = self.Type.String()
+		}
+	})
+}
+
+// Inspects everything that is otherwise inspected lazily, including the whole chain, so that the
+// exception can be used without the GIL
+func (self *Exception) inspectAll() {
+	self.Frames()
+	if cause := self.Cause(); cause != nil {
+		cause.inspectAll()
+	}
+	if context := self.Context(); context != nil {
+		context.inspectAll()
+	}
+}
+
+// Calls inspectAll on the *Exception in the error chain, if there is one
+func inspectError(err error) {
+	var exception *Exception
+	if errors.As(err, &exception) {
+		exception.inspectAll()
+	}
+}
+
+func (self *Exception) inspectChain() {
+	if self.chainInspected {
+		return
+	}
+	self.chainInspected = true
+
+	if (self.Value == nil) || (self.depth >= MAX_EXCEPTION_CHAIN) {
+		return
+	}
+
+	withoutException(func() {
+		if cause := C.PyException_GetCause(self.Value.Object); cause != nil {
+			self.cause = newExceptionFromValue(cause, self.depth+1)
+		}
+
+		if context := C.PyException_GetContext(self.Value.Object); context != nil {
+			self.context = newExceptionFromValue(context, self.depth+1)
+		}
+
+		if suppressContext := getAttrRaw(self.Value.Object, "__suppress_context__"); suppressContext != nil {
+			self.suppressContext = suppressContext == C.Py_True
+			C.Py_DecRef(suppressContext)
+		}
+	})
+}
+
+// Steals the value reference
+func newExceptionFromValue(value *C.PyObject, depth int) *Exception {
+	type_ := (*C.PyObject)(unsafe.Pointer(value.ob_type))
+	C.Py_IncRef(type_)
+
+	var traceback *Reference
+	if traceback_ := C.PyException_GetTraceback(value); traceback_ != nil {
+		traceback = NewReference(traceback_)
+	}
+
+	exception := NewExceptionRaw(NewReference(type_), NewReference(value), traceback)
+	exception.depth = depth
+	return exception
+}
+
+//
+// TracebackFrame
+//
+
+type TracebackFrame struct {
+	Filename string
+	Line     int
+	Function string
+	Source   string // stripped; empty if unavailable
+}
+
+// fmt.Stringer interface
+func (self TracebackFrame) String() string {
+	return fmt.Sprintf("%s:%d in %s", self.Filename, self.Line, self.Function)
+}
+
+func getTracebackFrames(traceback *C.PyObject) []TracebackFrame {
+	var frames []TracebackFrame
+
+	linecache_ := C.CString("linecache")
+	defer C.free(unsafe.Pointer(linecache_))
+
+	linecache := C.PyImport_ImportModule(linecache_)
+	if linecache == nil {
+		C.PyErr_Clear()
+	} else {
+		defer C.Py_DecRef(linecache)
+	}
+
+	C.Py_IncRef(traceback)
+	for (traceback != nil) && (traceback != C.Py_None) {
+		var frame TracebackFrame
+		var globals *C.PyObject
+
+		if line := getAttrRaw(traceback, "tb_lineno"); line != nil {
+			frame.Line = int(C.PyLong_AsLong(line))
+			C.Py_DecRef(line)
+		}
+
+		if frame_ := getAttrRaw(traceback, "tb_frame"); frame_ != nil {
+			if code := getAttrRaw(frame_, "f_code"); code != nil {
+				frame.Filename = getStringAttrRaw(code, "co_filename")
+				frame.Function = getStringAttrRaw(code, "co_name")
+				C.Py_DecRef(code)
+			}
+			globals = getAttrRaw(frame_, "f_globals")
+			C.Py_DecRef(frame_)
+		}
+
+		if (linecache != nil) && (frame.Filename != "") {
+			frame.Source = getSourceLine(linecache, frame.Filename, frame.Line, globals)
+		}
+		C.Py_DecRef(globals) // handles NULL
+
+		frames = append(frames, frame)
+
+		next := getAttrRaw(traceback, "tb_next")
+		C.Py_DecRef(traceback)
+		traceback = next
+	}
+	C.Py_DecRef(traceback) // handles NULL
+
+	C.PyErr_Clear()
+	return frames
+}
+
+// The module globals allow linecache to get the source from the module's loader, e.g. for
+// modules that are not on disk
+func getSourceLine(linecache *C.PyObject, filename string, line int, globals *C.PyObject) string {
+	if getline := getAttrRaw(linecache, "getline"); getline != nil {
+		defer C.Py_DecRef(getline)
+
+		var globals_ interface{}
+		if globals != nil {
+			globals_ = NewBorrowedReference(globals)
+		}
+
+		if args, err := NewTuple(filename, line, globals_); err == nil {
+			defer args.Release()
+
+			if source := C.PyObject_CallObject(getline, args.Object); source != nil {
+				defer C.Py_DecRef(source)
+
+				if source_, err := NewBorrowedReference(source).ToString(); err == nil {
+					return strings.TrimSpace(source_)
+				}
+			}
+		}
+	}
+
+	C.PyErr_Clear()
+	return ""
+}
+
+// E.g. "KeyError" or "json.decoder.JSONDecodeError"
+func getQualifiedName(type_ *C.PyObject) string {
+	name := getStringAttrRaw(type_, "__qualname__")
+	if module := getStringAttrRaw(type_, "__module__"); (module != "") && (module != "builtins") && (module != "__main__") {
+		name = module + "." + name
+	}
+	return name
+}
+
+// Returns a new reference or nil, without leaving an exception
+func getAttrRaw(object *C.PyObject, name string) *C.PyObject {
+	name_ := C.CString(name)
+	defer C.free(unsafe.Pointer(name_))
+
+	if attr := C.PyObject_GetAttrString(object, name_); attr != nil {
+		return attr
+	} else {
+		C.PyErr_Clear()
+		return nil
+	}
+}
+
+// Returns an empty string on failure, without leaving an exception
+func getStringAttrRaw(object *C.PyObject, name string) string {
+	if attr := getAttrRaw(object, name); attr != nil {
+		defer C.Py_DecRef(attr)
+
+		if attr_ := NewBorrowedReference(attr); attr_.IsUnicode() {
+			if string_, err := attr_.ToString(); err == nil {
+				return string_
+			}
+		}
+	}
+	return ""
+}
+
+// Temporarily clears the current exception (if there is one) while calling the function,
+// because most of the Python API should not be called while an exception is set
+func withoutException(f func()) {
+	var type_, value, traceback *C.PyObject
+	C.PyErr_Fetch(&type_, &value, &traceback)
+	defer C.PyErr_Restore(type_, value, traceback)
+
+	f()
 }
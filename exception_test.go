@@ -0,0 +1,241 @@
+package python
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExceptionMatching(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		_, err := Eval("{}['missing']", nil, nil)
+
+		var exception *Exception
+		if !errors.As(err, &exception) {
+			t.Fatalf("expected an *Exception, got %v", err)
+		}
+		defer exception.Release()
+
+		if exception.TypeName != "KeyError" {
+			t.Errorf("expected KeyError, got %s", exception.TypeName)
+		}
+		if !exception.Matches(ExcKeyError) || !exception.Matches(ExcLookupError) || exception.Matches(ExcValueError) {
+			t.Error("unexpected Matches")
+		}
+
+		// Subclasses match
+		if !errors.Is(err, ErrKeyError) || !errors.Is(err, ErrLookupError) || !errors.Is(err, ErrException) {
+			t.Error("expected errors.Is to match KeyError and its base classes")
+		}
+		if errors.Is(err, ErrValueError) {
+			t.Error("expected errors.Is not to match ValueError")
+		}
+
+		// Wrapping does not get in the way
+		if !errors.Is(fmt.Errorf("wrapped: %w", err), ErrKeyError) {
+			t.Error("expected errors.Is to match a wrapped KeyError")
+		}
+	})
+}
+
+func TestExceptionCauseAndContext(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newGlobals(t, nil)
+		defer globals.Release()
+
+		if err := Exec(`
+def with_cause():
+    try:
+        {}['missing']
+    except KeyError as e:
+        raise ValueError('bad') from e
+
+def with_context():
+    try:
+        {}['missing']
+    except KeyError:
+        raise ValueError('bad')
+
+def with_suppressed_context():
+    try:
+        {}['missing']
+    except KeyError:
+        raise ValueError('bad') from None
+`, globals, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// errors.Is follows the same chain as Python's traceback output
+		_, err := Eval("with_cause()", globals, nil)
+		if !errors.Is(err, ErrValueError) || !errors.Is(err, ErrKeyError) {
+			t.Errorf("expected ValueError caused by KeyError, got %v", err)
+		}
+
+		var exception *Exception
+		if errors.As(err, &exception) {
+			if (exception.Cause() == nil) || (exception.Cause().TypeName != "KeyError") {
+				t.Error("expected a KeyError cause")
+			}
+		}
+		releaseError(err)
+
+		_, err = Eval("with_context()", globals, nil)
+		if !errors.Is(err, ErrValueError) || !errors.Is(err, ErrKeyError) {
+			t.Errorf("expected ValueError in the context of KeyError, got %v", err)
+		}
+
+		if errors.As(err, &exception) {
+			if exception.Cause() != nil {
+				t.Error("expected no cause")
+			}
+			if (exception.Context() == nil) || (exception.Context().TypeName != "KeyError") {
+				t.Error("expected a KeyError context")
+			}
+		}
+		releaseError(err)
+
+		_, err = Eval("with_suppressed_context()", globals, nil)
+		if !errors.Is(err, ErrValueError) || errors.Is(err, ErrKeyError) {
+			t.Errorf("expected ValueError not matching KeyError, got %v", err)
+		}
+
+		if errors.As(err, &exception) {
+			if !exception.SuppressContext() || (exception.Context() == nil) {
+				t.Error("expected a suppressed context")
+			}
+		}
+		releaseError(err)
+	})
+}
+
+func TestExceptionFormat(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		_, err := Eval("int('x')", nil, nil)
+
+		var exception *Exception
+		if !errors.As(err, &exception) {
+			t.Fatalf("expected an *Exception, got %v", err)
+		}
+		defer exception.Release()
+
+		if format, err := exception.Format(); err == nil {
+			if !strings.HasPrefix(format, "Traceback (most recent call last):\n") || !strings.HasSuffix(format, "ValueError: invalid literal for int() with base 10: 'x'\n") {
+				t.Errorf("unexpected format %q", format)
+			}
+		} else {
+			t.Error(err)
+		}
+
+		// Without a traceback
+		exception = NewExceptionRaw(eval(t, "ValueError", nil), eval(t, "ValueError('bad')", nil), nil)
+		defer exception.Release()
+		if format, err := exception.Format(); err == nil {
+			if format != "ValueError: bad\n" {
+				t.Errorf("unexpected format %q", format)
+			}
+		} else {
+			t.Error(err)
+		}
+	})
+}
+
+func TestExceptionRestore(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		RaiseError(ExcValueError, "restored")
+
+		exception := FetchException()
+		if exception == nil {
+			t.Fatal("expected an exception")
+		}
+		defer exception.Release()
+		if HasException() {
+			t.Fatal("expected FetchException to clear the exception")
+		}
+
+		exception.Restore()
+		if !HasException() {
+			t.Fatal("expected Restore to set the exception")
+		}
+
+		err := GetError()
+		if HasException() {
+			t.Error("expected GetError to clear the exception")
+		}
+		if !errors.Is(err, ErrValueError) || (err.Error() != "restored") {
+			t.Errorf("unexpected error %v", err)
+		}
+		releaseError(err)
+
+		if (FetchException() != nil) || (PeekException() != nil) {
+			t.Error("expected no exception")
+		}
+
+		RaiseError(ExcTypeError, "cleared")
+		ClearError()
+		if HasException() {
+			t.Error("expected ClearError to clear the exception")
+		}
+	})
+}
+
+func TestRaiseGoError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, test := range []struct {
+			err    error
+			target *Reference
+		}{
+			{errors.New("plain"), ExcRuntimeError},
+			{ErrKeyError, ExcKeyError},
+			{fmt.Errorf("bad port: %w", ErrValueError), ExcValueError},
+			{newTypeError("typed"), ExcTypeError},
+		} {
+			RaiseGoError(test.err)
+
+			if exception := FetchException(); exception != nil {
+				if !exception.Matches(test.target) {
+					t.Errorf("%v: expected %s, got %s", test.err, test.target.String(), exception.TypeName)
+				}
+				exception.Release()
+			} else {
+				t.Errorf("%v: expected an exception", test.err)
+			}
+		}
+
+		// An *Exception is re-raised as is
+		_, err := Eval("int('x')", nil, nil)
+		defer releaseError(err)
+		RaiseGoError(fmt.Errorf("wrapped: %w", err))
+
+		var exception *Exception
+		errors.As(err, &exception)
+		if exception_ := FetchException(); (exception_ == nil) || (exception_.Value.Object != exception.Value.Object) {
+			t.Error("expected the original exception")
+		} else {
+			exception_.Release()
+		}
+	})
+}
+
+func TestStandardExceptionClassesBorrowed(t *testing.T) {
+	if ExcKeyError.Ownership() != Borrowed {
+		t.Errorf("expected borrowed, got %s", ExcKeyError.Ownership())
+	}
+
+	// A no-op
+	ExcKeyError.Release()
+
+	withGIL(t, func() {
+		assertPython(t, ExcKeyError, "value is KeyError")
+	})
+}
@@ -0,0 +1,188 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/exceptions.html#standard-exceptions
+//   https://docs.python.org/3/library/exceptions.html
+
+import (
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+//
+// ExceptionClass
+//
+
+// A Go error that represents a Python exception class, for use with errors.Is, e.g.:
+//
+//	if errors.Is(err, python.ErrKeyError) { ... }
+//
+// Matching respects subclassing, so ErrLookupError also matches KeyError exceptions. Note that
+// errors.Is also follows the chain of the exception, see Exception.Unwrap.
+type ExceptionClass struct {
+	Type *Reference
+
+	// Computed on creation, so that Error does not need the GIL
+	name string
+}
+
+// If Python is initialized the caller must hold the GIL.
+func NewExceptionClass(type_ *Reference) *ExceptionClass {
+	var name string
+	if C.Py_IsInitialized() != 0 {
+		name = getQualifiedName(type_.Object)
+	} else {
+		// Before initialization we can only use static types, which have their full name here
+		name = C.GoString((*C.PyTypeObject)(unsafe.Pointer(type_.Object)).tp_name)
+	}
+
+	return &ExceptionClass{
+		Type: type_,
+		name: name,
+	}
+}
+
+// error signature
+func (self *ExceptionClass) Error() string {
+	return "Python " + self.name
+}
+
+// Returns true if the exception is an instance of this class or of a subclass
+func (self *Exception) Matches(type_ *Reference) bool {
+	if self.Type != nil {
+		return C.PyErr_GivenExceptionMatches(self.Type.Object, type_.Object) != 0
+	} else {
+		return false
+	}
+}
+
+// errors.Is signature
+func (self *Exception) Is(target error) bool {
+	if class, ok := target.(*ExceptionClass); ok {
+		return self.Matches(class.Type)
+	} else {
+		return false
+	}
+}
+
+//
+// Standard exception classes
+//
+
+// Borrowed
+var (
+	ExcBaseException          = NewBorrowedReference(C.PyExc_BaseException)
+	ExcException              = NewBorrowedReference(C.PyExc_Exception)
+	ExcArithmeticError        = NewBorrowedReference(C.PyExc_ArithmeticError)
+	ExcAssertionError         = NewBorrowedReference(C.PyExc_AssertionError)
+	ExcAttributeError         = NewBorrowedReference(C.PyExc_AttributeError)
+	ExcBlockingIOError        = NewBorrowedReference(C.PyExc_BlockingIOError)
+	ExcBrokenPipeError        = NewBorrowedReference(C.PyExc_BrokenPipeError)
+	ExcBufferError            = NewBorrowedReference(C.PyExc_BufferError)
+	ExcChildProcessError      = NewBorrowedReference(C.PyExc_ChildProcessError)
+	ExcConnectionAbortedError = NewBorrowedReference(C.PyExc_ConnectionAbortedError)
+	ExcConnectionError        = NewBorrowedReference(C.PyExc_ConnectionError)
+	ExcConnectionRefusedError = NewBorrowedReference(C.PyExc_ConnectionRefusedError)
+	ExcConnectionResetError   = NewBorrowedReference(C.PyExc_ConnectionResetError)
+	ExcEOFError               = NewBorrowedReference(C.PyExc_EOFError)
+	ExcFileExistsError        = NewBorrowedReference(C.PyExc_FileExistsError)
+	ExcFileNotFoundError      = NewBorrowedReference(C.PyExc_FileNotFoundError)
+	ExcFloatingPointError     = NewBorrowedReference(C.PyExc_FloatingPointError)
+	ExcGeneratorExit          = NewBorrowedReference(C.PyExc_GeneratorExit)
+	ExcImportError            = NewBorrowedReference(C.PyExc_ImportError)
+	ExcIndentationError       = NewBorrowedReference(C.PyExc_IndentationError)
+	ExcIndexError             = NewBorrowedReference(C.PyExc_IndexError)
+	ExcInterruptedError       = NewBorrowedReference(C.PyExc_InterruptedError)
+	ExcIsADirectoryError      = NewBorrowedReference(C.PyExc_IsADirectoryError)
+	ExcKeyError               = NewBorrowedReference(C.PyExc_KeyError)
+	ExcKeyboardInterrupt      = NewBorrowedReference(C.PyExc_KeyboardInterrupt)
+	ExcLookupError            = NewBorrowedReference(C.PyExc_LookupError)
+	ExcMemoryError            = NewBorrowedReference(C.PyExc_MemoryError)
+	ExcModuleNotFoundError    = NewBorrowedReference(C.PyExc_ModuleNotFoundError)
+	ExcNameError              = NewBorrowedReference(C.PyExc_NameError)
+	ExcNotADirectoryError     = NewBorrowedReference(C.PyExc_NotADirectoryError)
+	ExcNotImplementedError    = NewBorrowedReference(C.PyExc_NotImplementedError)
+	ExcOSError                = NewBorrowedReference(C.PyExc_OSError)
+	ExcOverflowError          = NewBorrowedReference(C.PyExc_OverflowError)
+	ExcPermissionError        = NewBorrowedReference(C.PyExc_PermissionError)
+	ExcProcessLookupError     = NewBorrowedReference(C.PyExc_ProcessLookupError)
+	ExcRecursionError         = NewBorrowedReference(C.PyExc_RecursionError)
+	ExcReferenceError         = NewBorrowedReference(C.PyExc_ReferenceError)
+	ExcRuntimeError           = NewBorrowedReference(C.PyExc_RuntimeError)
+	ExcStopAsyncIteration     = NewBorrowedReference(C.PyExc_StopAsyncIteration)
+	ExcStopIteration          = NewBorrowedReference(C.PyExc_StopIteration)
+	ExcSyntaxError            = NewBorrowedReference(C.PyExc_SyntaxError)
+	ExcSystemError            = NewBorrowedReference(C.PyExc_SystemError)
+	ExcSystemExit             = NewBorrowedReference(C.PyExc_SystemExit)
+	ExcTabError               = NewBorrowedReference(C.PyExc_TabError)
+	ExcTimeoutError           = NewBorrowedReference(C.PyExc_TimeoutError)
+	ExcTypeError              = NewBorrowedReference(C.PyExc_TypeError)
+	ExcUnboundLocalError      = NewBorrowedReference(C.PyExc_UnboundLocalError)
+	ExcUnicodeDecodeError     = NewBorrowedReference(C.PyExc_UnicodeDecodeError)
+	ExcUnicodeEncodeError     = NewBorrowedReference(C.PyExc_UnicodeEncodeError)
+	ExcUnicodeError           = NewBorrowedReference(C.PyExc_UnicodeError)
+	ExcUnicodeTranslateError  = NewBorrowedReference(C.PyExc_UnicodeTranslateError)
+	ExcValueError             = NewBorrowedReference(C.PyExc_ValueError)
+	ExcZeroDivisionError      = NewBorrowedReference(C.PyExc_ZeroDivisionError)
+)
+
+var (
+	ErrBaseException          = NewExceptionClass(ExcBaseException)
+	ErrException              = NewExceptionClass(ExcException)
+	ErrArithmeticError        = NewExceptionClass(ExcArithmeticError)
+	ErrAssertionError         = NewExceptionClass(ExcAssertionError)
+	ErrAttributeError         = NewExceptionClass(ExcAttributeError)
+	ErrBlockingIOError        = NewExceptionClass(ExcBlockingIOError)
+	ErrBrokenPipeError        = NewExceptionClass(ExcBrokenPipeError)
+	ErrBufferError            = NewExceptionClass(ExcBufferError)
+	ErrChildProcessError      = NewExceptionClass(ExcChildProcessError)
+	ErrConnectionAbortedError = NewExceptionClass(ExcConnectionAbortedError)
+	ErrConnectionError        = NewExceptionClass(ExcConnectionError)
+	ErrConnectionRefusedError = NewExceptionClass(ExcConnectionRefusedError)
+	ErrConnectionResetError   = NewExceptionClass(ExcConnectionResetError)
+	ErrEOFError               = NewExceptionClass(ExcEOFError)
+	ErrFileExistsError        = NewExceptionClass(ExcFileExistsError)
+	ErrFileNotFoundError      = NewExceptionClass(ExcFileNotFoundError)
+	ErrFloatingPointError     = NewExceptionClass(ExcFloatingPointError)
+	ErrGeneratorExit          = NewExceptionClass(ExcGeneratorExit)
+	ErrImportError            = NewExceptionClass(ExcImportError)
+	ErrIndentationError       = NewExceptionClass(ExcIndentationError)
+	ErrIndexError             = NewExceptionClass(ExcIndexError)
+	ErrInterruptedError       = NewExceptionClass(ExcInterruptedError)
+	ErrIsADirectoryError      = NewExceptionClass(ExcIsADirectoryError)
+	ErrKeyError               = NewExceptionClass(ExcKeyError)
+	ErrKeyboardInterrupt      = NewExceptionClass(ExcKeyboardInterrupt)
+	ErrLookupError            = NewExceptionClass(ExcLookupError)
+	ErrMemoryError            = NewExceptionClass(ExcMemoryError)
+	ErrModuleNotFoundError    = NewExceptionClass(ExcModuleNotFoundError)
+	ErrNameError              = NewExceptionClass(ExcNameError)
+	ErrNotADirectoryError     = NewExceptionClass(ExcNotADirectoryError)
+	ErrNotImplementedError    = NewExceptionClass(ExcNotImplementedError)
+	ErrOSError                = NewExceptionClass(ExcOSError)
+	ErrOverflowError          = NewExceptionClass(ExcOverflowError)
+	ErrPermissionError        = NewExceptionClass(ExcPermissionError)
+	ErrProcessLookupError     = NewExceptionClass(ExcProcessLookupError)
+	ErrRecursionError         = NewExceptionClass(ExcRecursionError)
+	ErrReferenceError         = NewExceptionClass(ExcReferenceError)
+	ErrRuntimeError           = NewExceptionClass(ExcRuntimeError)
+	ErrStopAsyncIteration     = NewExceptionClass(ExcStopAsyncIteration)
+	ErrStopIteration          = NewExceptionClass(ExcStopIteration)
+	ErrSyntaxError            = NewExceptionClass(ExcSyntaxError)
+	ErrSystemError            = NewExceptionClass(ExcSystemError)
+	ErrSystemExit             = NewExceptionClass(ExcSystemExit)
+	ErrTabError               = NewExceptionClass(ExcTabError)
+	ErrTimeoutError           = NewExceptionClass(ExcTimeoutError)
+	ErrTypeError              = NewExceptionClass(ExcTypeError)
+	ErrUnboundLocalError      = NewExceptionClass(ExcUnboundLocalError)
+	ErrUnicodeDecodeError     = NewExceptionClass(ExcUnicodeDecodeError)
+	ErrUnicodeEncodeError     = NewExceptionClass(ExcUnicodeEncodeError)
+	ErrUnicodeError           = NewExceptionClass(ExcUnicodeError)
+	ErrUnicodeTranslateError  = NewExceptionClass(ExcUnicodeTranslateError)
+	ErrValueError             = NewExceptionClass(ExcValueError)
+	ErrZeroDivisionError      = NewExceptionClass(ExcZeroDivisionError)
+)
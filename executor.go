@@ -0,0 +1,234 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+var ErrExecutorShutdown = errors.New("executor is shut down")
+var ErrQueueFull = errors.New("executor queue is full")
+
+//
+// Executor
+//
+
+// Runs functions on a dedicated OS thread that owns the interpreter, in the order in which they
+// were submitted. This is simpler and often faster than having many goroutines contend for the
+// GIL. The GIL is released while the executor is idle, so other threads can still use Python.
+//
+// If Python is not yet initialized the executor initializes it when created and finalizes it when
+// shut down. Otherwise (e.g. if you need InitializeWithConfig) it attaches to the existing
+// interpreter, in which case the initializing thread must release the GIL (see SaveThreadState)
+// for the executor to run.
+type Executor struct {
+	queue       chan *executorTask
+	closing     chan struct{}
+	done        chan struct{}
+	closed      bool
+	submitting  sync.WaitGroup
+	finalizeErr error
+	lock        sync.RWMutex
+}
+
+type executorTask struct {
+	function func() (interface{}, error)
+	future   *Future
+}
+
+// The queue depth is the number of functions that can be waiting to run before Submit blocks
+// (backpressure). It can be 0, in which case Submit blocks until the executor is ready to run
+// the function.
+func NewExecutor(queueDepth int) (*Executor, error) {
+	self := Executor{
+		queue:   make(chan *executorTask, queueDepth),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	initialized := make(chan error)
+	go self.run(initialized)
+	if err := <-initialized; err == nil {
+		return &self, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Queues the function to run on the executor's thread. If the queue is full it blocks until
+// there is room, until the context is done, or until the executor is shut down.
+func (self *Executor) Submit(context_ context.Context, function func() (interface{}, error)) (*Future, error) {
+	self.lock.RLock()
+	if self.closed {
+		self.lock.RUnlock()
+		return nil, ErrExecutorShutdown
+	}
+	// Shutdown waits for us before closing the queue
+	self.submitting.Add(1)
+	self.lock.RUnlock()
+	defer self.submitting.Done()
+
+	task := executorTask{function, newFuture()}
+	select {
+	case self.queue <- &task:
+		return task.future, nil
+	case <-self.closing:
+		return nil, ErrExecutorShutdown
+	case <-context_.Done():
+		return nil, context_.Err()
+	}
+}
+
+// Like Submit but returns ErrQueueFull instead of blocking
+func (self *Executor) TrySubmit(function func() (interface{}, error)) (*Future, error) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if self.closed {
+		return nil, ErrExecutorShutdown
+	}
+
+	task := executorTask{function, newFuture()}
+	select {
+	case self.queue <- &task:
+		return task.future, nil
+	default:
+		return nil, ErrQueueFull
+	}
+}
+
+// Submits the function and waits for its result
+func (self *Executor) Run(context_ context.Context, function func() (interface{}, error)) (interface{}, error) {
+	if future, err := self.Submit(context_, function); err == nil {
+		return future.Wait(context_)
+	} else {
+		return nil, err
+	}
+}
+
+// Number of functions waiting to run
+func (self *Executor) QueueLength() int {
+	return len(self.queue)
+}
+
+// Stops accepting new functions, waits for the queued ones to run, and then finalizes Python if
+// the executor initialized it, returning the error from Finalize. If the context is done before
+// then its error is returned, but the executor will still continue shutting down in the
+// background.
+func (self *Executor) Shutdown(context_ context.Context) error {
+	self.lock.Lock()
+	if !self.closed {
+		self.closed = true
+		close(self.closing)
+		go func() {
+			// Blocked submitters will return once they see that we are closing
+			self.submitting.Wait()
+			close(self.queue)
+		}()
+	}
+	self.lock.Unlock()
+
+	select {
+	case <-self.done:
+		return self.finalizeErr
+	case <-context_.Done():
+		return context_.Err()
+	}
+}
+
+func (self *Executor) run(initialized chan<- error) {
+	defer close(self.done)
+
+	// The interpreter's thread state belongs to this thread, so we never unlock it
+	runtime.LockOSThread()
+
+	var gilState *GilState
+	if C.Py_IsInitialized() == 0 {
+		if err := Initialize(); err != nil {
+			initialized <- err
+			return
+		}
+		if C.Py_IsInitialized() == 0 {
+			initialized <- errors.New("could not initialize Python")
+			return
+		}
+	} else {
+		gilState = EnsureGilState()
+	}
+	initialized <- nil
+
+	threadState := SaveThreadState()
+
+	for task := range self.queue {
+		threadState.Restore()
+		task.run()
+		threadState = SaveThreadState()
+	}
+
+	threadState.Restore()
+	if gilState != nil {
+		gilState.Release()
+	} else {
+		self.finalizeErr = Finalize()
+	}
+}
+
+func (self *executorTask) run() {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			self.future.resolve(nil, fmt.Errorf("Go panic: %v", recovered))
+		}
+	}()
+
+	value, err := self.function()
+
+	// The caller will not have the GIL
+	inspectError(err)
+
+	self.future.resolve(value, err)
+}
+
+//
+// Future
+//
+
+// The eventual result of a function submitted to an Executor
+type Future struct {
+	value interface{}
+	err   error
+	done  chan struct{}
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Closed when the result is available
+func (self *Future) Done() <-chan struct{} {
+	return self.done
+}
+
+// Waits for the result or until the context is done. A Python *Exception in the error has already
+// been fully inspected on the executor's thread, so it can be used without the GIL.
+func (self *Future) Wait(context_ context.Context) (interface{}, error) {
+	select {
+	case <-self.done:
+		return self.value, self.err
+	case <-context_.Done():
+		return nil, context_.Err()
+	}
+}
+
+func (self *Future) resolve(value interface{}, err error) {
+	self.value = value
+	self.err = err
+	close(self.done)
+}
@@ -0,0 +1,145 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestExecutor(t *testing.T, queueDepth int) *Executor {
+	t.Helper()
+
+	if executor, err := NewExecutor(queueDepth); err == nil {
+		t.Cleanup(func() {
+			if err := executor.Shutdown(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+		return executor
+	} else {
+		t.Fatal(err)
+		return nil
+	}
+}
+
+func TestExecutorRun(t *testing.T) {
+	CheckLeaks(t)
+
+	executor := newTestExecutor(t, 0)
+
+	if value, err := executor.Run(context.Background(), func() (interface{}, error) {
+		if result, err := Eval("6 * 7", nil, nil); err == nil {
+			defer result.Release()
+			return result.ToInt64()
+		} else {
+			return nil, err
+		}
+	}); err == nil {
+		if value != int64(42) {
+			t.Errorf("expected 42, got %v", value)
+		}
+	} else {
+		t.Fatal(err)
+	}
+}
+
+func TestExecutorException(t *testing.T) {
+	executor := newTestExecutor(t, 0)
+
+	_, err := executor.Run(context.Background(), func() (interface{}, error) {
+		return nil, Exec("def f():\n    raise KeyError('missing')\nf()\n", nil, nil)
+	})
+	defer executor.Run(context.Background(), func() (interface{}, error) {
+		releaseError(err)
+		return nil, nil
+	})
+
+	// We do not have the GIL here
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if err.Error() != "'missing'" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if !errors.Is(err, ErrKeyError) {
+		t.Errorf("expected KeyError, got %s", err)
+	}
+
+	var exception *Exception
+	if errors.As(err, &exception) {
+		if exception.TypeName != "KeyError" {
+			t.Errorf("unexpected type name %q", exception.TypeName)
+		}
+		if frames := exception.Frames(); (len(frames) == 0) || (frames[len(frames)-1].Function != "f") {
+			t.Errorf("unexpected frames %v", frames)
+		}
+	} else {
+		t.Errorf("expected an *Exception, got %T", err)
+	}
+}
+
+func TestExecutorQueueFull(t *testing.T) {
+	executor := newTestExecutor(t, 1)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking, err := executor.Submit(context.Background(), func() (interface{}, error) {
+		close(started)
+		<-unblock
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// Fills the queue
+	queued, err := executor.TrySubmit(func() (interface{}, error) {
+		return "queued", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := executor.TrySubmit(func() (interface{}, error) { return nil, nil }); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	close(unblock)
+	if _, err := blocking.Wait(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if value, err := queued.Wait(context.Background()); (err != nil) || (value != "queued") {
+		t.Errorf("unexpected %v, %v", value, err)
+	}
+}
+
+func TestExecutorPanic(t *testing.T) {
+	executor := newTestExecutor(t, 0)
+
+	if _, err := executor.Run(context.Background(), func() (interface{}, error) {
+		panic("oops")
+	}); (err == nil) || (err.Error() != "Go panic: oops") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestExecutorShutdown(t *testing.T) {
+	executor, err := NewExecutor(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := executor.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := executor.Run(context.Background(), func() (interface{}, error) { return nil, nil }); err != ErrExecutorShutdown {
+		t.Errorf("expected ErrExecutorShutdown, got %v", err)
+	}
+
+	// Repeated shutdowns are harmless
+	if err := executor.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
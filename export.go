@@ -0,0 +1,156 @@
+package python
+
+// Here we export Go functions that are called from the C code in other files' cgo preambles
+
+// Note: cgo exports cannot be in the same file as cgo preamble functions
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/cgo"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+//export py4go_callGoFunction
+func py4go_callGoFunction(handle C.uintptr_t, args *C.PyObject, kw *C.PyObject) (r *C.PyObject) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+			r = nil
+		}
+	}()
+
+	function := cgo.Handle(handle).Value().(*GoFunction)
+
+	var kw_ *Reference
+	if kw != nil {
+		kw_ = NewBorrowedReference(kw)
+	}
+
+	if result, err := function.Call(NewBorrowedReference(args), kw_); err == nil {
+		return result.steal()
+	} else {
+		raiseReturnedError(err)
+		return nil
+	}
+}
+
+//export py4go_releaseGoFunction
+func py4go_releaseGoFunction(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+//export py4go_newGoObject
+func py4go_newGoObject(type_ *C.PyTypeObject, args *C.PyObject, kw *C.PyObject) (r C.uintptr_t) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+			r = 0
+		}
+	}()
+
+	if class := getGoClassByObject(type_); class != nil {
+		if C.PyTuple_Size(args) > 0 {
+			RaiseGoError(newTypeError("%s() takes only keyword arguments", class.Name))
+			return 0
+		}
+
+		value := reflect.New(class.Type.Elem())
+		if kw != nil {
+			for _, item := range getDictItems(NewBorrowedReference(kw)) {
+				if name, err := item[0].ToString(); err == nil {
+					if attribute := class.getAttribute(name); attribute != nil {
+						if err := attribute.set(value, item[1]); err != nil {
+							raiseReturnedError(err)
+							return 0
+						}
+					} else {
+						RaiseGoError(newTypeError("%s() got an unexpected keyword argument %q", class.Name, name))
+						return 0
+					}
+				} else {
+					raiseReturnedError(err)
+					return 0
+				}
+			}
+		}
+
+		return C.uintptr_t(cgo.NewHandle(value))
+	} else {
+		RaiseGoError(newTypeError("not a Go class"))
+		return 0
+	}
+}
+
+//export py4go_releaseGoObject
+func py4go_releaseGoObject(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+//export py4go_reprGoObject
+func py4go_reprGoObject(handle C.uintptr_t) (r *C.PyObject) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+			r = nil
+		}
+	}()
+
+	if stringer, ok := getGoObjectValue(handle).Interface().(fmt.Stringer); ok {
+		if repr, err := NewUnicode(stringer.String()); err == nil {
+			return repr.steal()
+		} else {
+			raiseReturnedError(err)
+			return nil
+		}
+	} else {
+		RaiseGoError(newTypeError("not a fmt.Stringer"))
+		return nil
+	}
+}
+
+//export py4go_getGoObjectAttribute
+func py4go_getGoObjectAttribute(handle C.uintptr_t, attribute C.uintptr_t) (r *C.PyObject) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+			r = nil
+		}
+	}()
+
+	attribute_ := cgo.Handle(attribute).Value().(*goAttribute)
+	if r, err := attribute_.get(getGoObjectValue(handle)); err == nil {
+		return r.steal()
+	} else {
+		raiseReturnedError(err)
+		return nil
+	}
+}
+
+//export py4go_setGoObjectAttribute
+func py4go_setGoObjectAttribute(handle C.uintptr_t, value *C.PyObject, attribute C.uintptr_t) (r C.int) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+			r = -1
+		}
+	}()
+
+	attribute_ := cgo.Handle(attribute).Value().(*goAttribute)
+	if value == nil {
+		RaiseGoError(newTypeError("cannot delete attribute %q", attribute_.name))
+		return -1
+	}
+
+	if err := attribute_.set(getGoObjectValue(handle), NewBorrowedReference(value)); err == nil {
+		return 0
+	} else {
+		raiseReturnedError(err)
+		return -1
+	}
+}
@@ -0,0 +1,138 @@
+package python
+
+// See:
+//   https://pkg.go.dev/runtime#SetFinalizer
+
+import (
+	"runtime"
+	"sync"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+var finalizers struct {
+	enabled bool
+	pending []*C.PyObject
+	wake    chan struct{}
+	done    chan struct{}
+	lock    sync.Mutex
+}
+
+// When enabled, new references will be released automatically when they are garbage collected
+// by Go (unless they were already released explicitly). The decrefs happen under the GIL on a
+// dedicated goroutine, so it is safe for the garbage collector to run on any thread.
+//
+// Note that Go makes no guarantees as to when (or even if) finalizers run, so explicit Release
+// calls or a Scope are still preferable for large objects.
+//
+// Finalize calls DisableFinalizers.
+func EnableFinalizers() {
+	finalizers.lock.Lock()
+	defer finalizers.lock.Unlock()
+
+	if finalizers.enabled {
+		return
+	}
+
+	finalizers.enabled = true
+	finalizers.wake = make(chan struct{}, 1)
+	finalizers.done = make(chan struct{})
+	go runFinalizers(finalizers.wake, finalizers.done)
+}
+
+// Stops automatic releasing for new references and releases all references that have already been
+// garbage collected. Can be called whether or not the current thread holds the GIL.
+//
+// References created while finalizers were enabled that are garbage collected afterwards will
+// be leaked.
+func DisableFinalizers() {
+	finalizers.lock.Lock()
+	if !finalizers.enabled {
+		finalizers.lock.Unlock()
+		return
+	}
+	finalizers.enabled = false
+	close(finalizers.wake)
+	done := finalizers.done
+	finalizers.lock.Unlock()
+
+	// The finalizer goroutine may be waiting for the GIL. (Note that PyGILState_Check can't tell
+	// us, because it always returns 1 once sub-interpreters have been created.)
+	if getCurrentInterpreter() != nil {
+		threadState := SaveThreadState()
+		<-done
+		threadState.Restore()
+	} else {
+		<-done
+	}
+
+	releasePendingFinalizers()
+}
+
+func FinalizersEnabled() bool {
+	finalizers.lock.Lock()
+	defer finalizers.lock.Unlock()
+
+	return finalizers.enabled
+}
+
+func (self *Reference) setFinalizer() {
+	if FinalizersEnabled() {
+		runtime.SetFinalizer(self, finalizeReference)
+	}
+}
+
+func (self *Reference) clearFinalizer() {
+	runtime.SetFinalizer(self, nil)
+}
+
+// Called on the Go runtime's finalizer goroutine, which must not block and does not hold the GIL
+func finalizeReference(reference *Reference) {
+	finalizers.lock.Lock()
+	defer finalizers.lock.Unlock()
+
+	if finalizers.enabled {
+		// Release all the references we hold
+		for count := 0; count < reference.count; count++ {
+			finalizers.pending = append(finalizers.pending, reference.Object)
+		}
+		select {
+		case finalizers.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func runFinalizers(wake chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	// The GIL state is per OS thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for range wake {
+		releasePendingFinalizers()
+	}
+}
+
+func releasePendingFinalizers() {
+	finalizers.lock.Lock()
+	pending := finalizers.pending
+	finalizers.pending = nil
+	finalizers.lock.Unlock()
+
+	if (len(pending) == 0) || (C.Py_IsInitialized() == 0) {
+		return
+	}
+
+	gilState := EnsureGilState()
+	defer gilState.Release()
+
+	for _, object := range pending {
+		C.Py_DecRef(object)
+	}
+}
@@ -0,0 +1,60 @@
+package python
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFinalizers(t *testing.T) {
+	if LeakTrackingEnabled() {
+		// Tracked references are kept alive
+		t.Skip("leak tracking is enabled")
+	}
+
+	EnableFinalizers()
+	defer DisableFinalizers()
+
+	globals := newTestFinalizerGlobals(t)
+	defer WithGIL(func() error {
+		globals.Release()
+		return nil
+	})
+
+	// Go's garbage collector needs a few rounds and the finalizer goroutine needs the GIL
+	for attempt := 0; attempt < 100; attempt++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+
+		var finalized bool
+		withGIL(t, func() {
+			result := eval(t, "globals['r']() is None", map[string]interface{}{"globals": globals})
+			finalized = result.ToBool()
+			result.Release()
+		})
+		if finalized {
+			return
+		}
+	}
+
+	t.Error("expected the reference to be finalized")
+}
+
+// Leaves no Go references to the object, other than the one we expect to be finalized
+func newTestFinalizerGlobals(t *testing.T) *Reference {
+	var globals *Reference
+	withGIL(t, func() {
+		globals = newGlobals(t, nil)
+		if err := Exec("import weakref\nclass C: pass\nc = C()\nr = weakref.ref(c)\n", globals, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Dropped without releasing it
+		getGlobal(t, globals, "c")
+
+		if err := Exec("del c", globals, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return globals
+}
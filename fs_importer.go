@@ -0,0 +1,489 @@
+package python
+
+// See:
+//   https://docs.python.org/3/library/importlib.html#importlib.abc.MetaPathFinder
+//   https://docs.python.org/3/library/importlib.resources.abc.html
+//   https://docs.python.org/3/library/importlib.html#importlib.abc.InspectLoader
+//   https://docs.python.org/3/library/importlib.resources.abc.html#importlib.resources.abc.Traversable
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+//
+// FSImporter
+//
+
+// Imports Python modules and packages (directories with "__init__.py") from an fs.FS, such as an
+// embed.FS, so that they need not be on disk. Package resources can be read via
+// importlib.resources.
+//
+// The modules' __file__ is the name followed by the path in the fs.FS, e.g. "embed/foo.py".
+//
+// Note that go:embed excludes files beginning with "_" in embedded directories, such as
+// "__init__.py", unless you use the "all:" prefix, e.g. "//go:embed all:python".
+type FSImporter struct {
+	Name   string
+	FS     fs.FS
+	Finder *Reference // nil after Remove
+}
+
+// Adds the importer to the end of sys.meta_path, meaning that modules found elsewhere take
+// precedence
+func AddFSImporter(name string, fsys fs.FS) (*FSImporter, error) {
+	return addFSImporter(name, fsys, false)
+}
+
+// Adds the importer to the beginning of sys.meta_path, meaning that its modules take precedence
+func PrependFSImporter(name string, fsys fs.FS) (*FSImporter, error) {
+	return addFSImporter(name, fsys, true)
+}
+
+func addFSImporter(name string, fsys fs.FS, prepend bool) (*FSImporter, error) {
+	self := FSImporter{
+		Name: name,
+		FS:   fsys,
+	}
+
+	if err := self.createFinder(); err != nil {
+		return nil, err
+	}
+
+	if metaPath, err := getSysList("meta_path"); err == nil {
+		var result C.int
+		if prepend {
+			result = C.PyList_Insert(metaPath.Object, 0, self.Finder.Object)
+		} else {
+			result = C.PyList_Append(metaPath.Object, self.Finder.Object)
+		}
+
+		if result == 0 {
+			return &self, nil
+		} else {
+			self.Finder.Release()
+			return nil, GetError()
+		}
+	} else {
+		self.Finder.Release()
+		return nil, err
+	}
+}
+
+// Removes the importer from sys.meta_path. Modules that were already imported remain in
+// sys.modules. Repeated calls are no-ops.
+func (self *FSImporter) Remove() error {
+	if self.Finder == nil {
+		return nil
+	}
+
+	if metaPath, err := getSysList("meta_path"); err == nil {
+		if remove, err := metaPath.GetAttr("remove"); err == nil {
+			defer remove.Release()
+
+			if r, err := remove.Call(self.Finder); err == nil {
+				r.Release()
+				self.Finder.Release()
+				self.Finder = nil
+				return nil
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+func (self *FSImporter) createFinder() error {
+	if class, err := getHelperGoClass("py4go.FSImporter", (*fsFinder)(nil), nil); err == nil {
+		self.Finder, err = class.NewObject(&fsFinder{self})
+		return err
+	} else {
+		return err
+	}
+}
+
+func (self *FSImporter) isDir(path string) bool {
+	info, err := fs.Stat(self.FS, fsPath(path))
+	return (err == nil) && info.IsDir()
+}
+
+func (self *FSImporter) isFile(path string) bool {
+	info, err := fs.Stat(self.FS, fsPath(path))
+	return (err == nil) && !info.IsDir()
+}
+
+// Errors satisfying errors.Is(err, fs.ErrNotExist) are raised as FileNotFoundError
+func (self *FSImporter) read(path string) ([]byte, error) {
+	return fs.ReadFile(self.FS, fsPath(path))
+}
+
+// The location is used for __file__ and in tracebacks
+func (self *FSImporter) location(path string) string {
+	if path == "" {
+		return self.Name
+	} else {
+		return self.Name + "/" + path
+	}
+}
+
+//
+// fsFinder
+//
+
+// A Python meta path finder
+type fsFinder struct {
+	importer *FSImporter
+}
+
+// fmt.Stringer interface
+func (self *fsFinder) String() string {
+	return fmt.Sprintf("<py4go.FSImporter %q>", self.importer.Name)
+}
+
+// Python calls it with the optional "path" and "target" arguments, which we ignore
+func (self *fsFinder) FindSpec(fullname string, arguments Arguments) (*Reference, error) {
+	base := strings.ReplaceAll(fullname, ".", "/")
+
+	var loader fsLoader
+	if self.importer.isDir(base) && self.importer.isFile(base+"/__init__.py") {
+		loader = fsLoader{self.importer, base + "/__init__.py", true}
+	} else if self.importer.isFile(base + ".py") {
+		loader = fsLoader{self.importer, base + ".py", false}
+	} else {
+		// Will be None
+		return nil, nil
+	}
+
+	scope := NewScope()
+	defer scope.Close()
+
+	class, err := getHelperGoClass("py4go.FSLoader", (*fsLoader)(nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	loader_, err := scope.Track(class.NewObject(&loader))
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := scope.Track(callModuleAttr("importlib.util", "spec_from_loader", []interface{}{fullname, loader_}, map[string]interface{}{
+		"origin":     self.importer.location(loader.filename),
+		"is_package": loader.isPackage,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := spec.SetAttr("has_location", scope.Add(newAcquiredReference(C.Py_True))); err != nil {
+		return nil, err
+	}
+
+	if loader.isPackage {
+		if locations, err := scope.Track(NewReferenceFromValue([]string{self.importer.location(base)})); err == nil {
+			if err := spec.SetAttr("submodule_search_locations", locations); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	return scope.Keep(spec), nil
+}
+
+func (self *fsFinder) InvalidateCaches() {
+}
+
+//
+// fsLoader
+//
+
+// A Python loader that also supports importlib.resources for packages
+type fsLoader struct {
+	importer  *FSImporter
+	filename  string
+	isPackage bool
+}
+
+// Returns nil for the default module creation
+func (self *fsLoader) CreateModule(spec *Reference) *Reference {
+	return nil
+}
+
+func (self *fsLoader) ExecModule(module *Reference) error {
+	if code, err := self.GetCode(""); err == nil {
+		defer code.Release()
+
+		if dict, err := module.GetAttr("__dict__"); err == nil {
+			defer dict.Release()
+
+			if r, err := EvalCode(code, dict, nil); err == nil {
+				r.Release()
+				return nil
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Unlike CompileSource this respects the source's encoding declaration
+func (self *fsLoader) GetCode(fullname string) (*Reference, error) {
+	if source, err := self.importer.read(self.filename); err == nil {
+		// compile(source, filename, mode, flags, dont_inherit)
+		return callModuleAttr("builtins", "compile", []interface{}{source, self.importer.location(self.filename), "exec", 0, true}, nil)
+	} else {
+		return nil, err
+	}
+}
+
+func (self *fsLoader) GetSource(fullname string) (*Reference, error) {
+	if source, err := self.importer.read(self.filename); err == nil {
+		return callModuleAttr("importlib.util", "decode_source", []interface{}{source}, nil)
+	} else {
+		return nil, err
+	}
+}
+
+func (self *fsLoader) IsPackage(fullname string) bool {
+	return self.isPackage
+}
+
+// Python calls it with an optional "fullname" argument, which we ignore
+func (self *fsLoader) GetFilename(arguments Arguments) string {
+	return self.importer.location(self.filename)
+}
+
+func (self *fsLoader) GetData(location string) ([]byte, error) {
+	if prefix := self.importer.Name + "/"; strings.HasPrefix(location, prefix) {
+		return self.importer.read(location[len(prefix):])
+	} else {
+		return nil, &fs.PathError{Op: "open", Path: location, Err: fs.ErrNotExist}
+	}
+}
+
+// Returns nil if we are not a package
+func (self *fsLoader) GetResourceReader(fullname string) (*Reference, error) {
+	if self.isPackage {
+		if class, err := getHelperGoClass("py4go.FSResources", (*fsResources)(nil), nil); err == nil {
+			return class.NewObject(&fsResources{self.importer, path.Dir(self.filename)})
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, nil
+	}
+}
+
+//
+// fsResources
+//
+
+type fsResources struct {
+	importer *FSImporter
+	path     string
+}
+
+func (self *fsResources) Files() (*Reference, error) {
+	return newFSResource(self.importer, self.path)
+}
+
+//
+// fsResource
+//
+
+// A Python traversable
+type fsResource struct {
+	Name string
+
+	importer *FSImporter
+	path     string
+}
+
+func newFSResource(importer *FSImporter, path_ string) (*Reference, error) {
+	if class, err := getHelperGoClass("py4go.FSResource", (*fsResource)(nil), map[string]interface{}{
+		"__str__": (*fsResource).location,
+		"__truediv__": func(self *fsResource, child *Reference) (*Reference, error) {
+			return self.Joinpath(child)
+		},
+	}); err == nil {
+		return class.NewObject(&fsResource{
+			Name:     path.Base(path_),
+			importer: importer,
+			path:     path_,
+		})
+	} else {
+		return nil, err
+	}
+}
+
+// fmt.Stringer interface
+func (self *fsResource) String() string {
+	return fmt.Sprintf("<py4go.FSResource %q>", self.location())
+}
+
+func (self *fsResource) location() string {
+	return self.importer.location(self.path)
+}
+
+func (self *fsResource) Iterdir() (*Reference, error) {
+	if entries, err := fs.ReadDir(self.importer.FS, fsPath(self.path)); err == nil {
+		resources := make([]*Reference, 0, len(entries))
+		defer func() {
+			for _, resource := range resources {
+				resource.Release()
+			}
+		}()
+
+		for _, entry := range entries {
+			if resource, err := newFSResource(self.importer, path.Join(self.path, entry.Name())); err == nil {
+				resources = append(resources, resource)
+			} else {
+				return nil, err
+			}
+		}
+
+		if list, err := NewReferenceFromValue(resources); err == nil {
+			defer list.Release()
+
+			if iterator := C.PyObject_GetIter(list.Object); iterator != nil {
+				return NewReference(iterator), nil
+			} else {
+				return nil, GetError()
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+func (self *fsResource) IsDir() bool {
+	return self.importer.isDir(self.path)
+}
+
+func (self *fsResource) IsFile() bool {
+	return self.importer.isFile(self.path)
+}
+
+// The descendants can be strings or path-like objects and can contain "/"
+func (self *fsResource) Joinpath(descendants ...*Reference) (*Reference, error) {
+	path_ := self.path
+	for _, descendant := range descendants {
+		if str, err := descendant.Str(); err == nil {
+			path_ = path.Join(path_, str.String())
+			str.Release()
+		} else {
+			return nil, err
+		}
+	}
+	return newFSResource(self.importer, path_)
+}
+
+func (self *fsResource) ReadBytes() ([]byte, error) {
+	return self.importer.read(self.path)
+}
+
+// Python calls it with the optional "encoding" and "errors" arguments
+func (self *fsResource) ReadText(arguments Arguments) (*Reference, error) {
+	if stream, err := self.newTextStream(arguments); err == nil {
+		defer stream.Release()
+
+		if read, err := stream.GetAttr("read"); err == nil {
+			defer read.Release()
+			return read.Call()
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Python calls it with an optional "mode" argument (only "r" and "rb" are supported) followed by
+// the io.TextIOWrapper arguments
+func (self *fsResource) Open(arguments Arguments) (*Reference, error) {
+	mode := "r"
+	if mode_ := arguments.Get(0, "mode"); mode_ != nil {
+		var err error
+		if mode, err = mode_.ToString(); err != nil {
+			return nil, err
+		}
+
+		if len(arguments.Positional) > 0 {
+			arguments.Positional = arguments.Positional[1:]
+		} else {
+			delete(arguments.Keywords, "mode")
+		}
+	}
+
+	switch mode {
+	case "r":
+		return self.newTextStream(arguments)
+
+	case "rb":
+		if data, err := self.ReadBytes(); err == nil {
+			return callModuleAttr("io", "BytesIO", []interface{}{data}, nil)
+		} else {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported mode %q", ErrValueError, mode)
+	}
+}
+
+// The arguments are those of io.TextIOWrapper after the buffer
+func (self *fsResource) newTextStream(arguments Arguments) (*Reference, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	data, err := self.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, err := scope.Track(callModuleAttr("io", "BytesIO", []interface{}{data}, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{buffer}
+	for _, arg := range arguments.Positional {
+		args = append(args, arg)
+	}
+
+	kw := make(map[string]interface{})
+	for name, value := range arguments.Keywords {
+		kw[name] = value
+	}
+
+	return callModuleAttr("io", "TextIOWrapper", args, kw)
+}
+
+// fs.FS paths are unrooted and "." is the root
+func fsPath(path_ string) string {
+	if path_ == "" {
+		return "."
+	} else {
+		return path.Clean(path_)
+	}
+}
@@ -0,0 +1,194 @@
+package python
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+var testFS = fstest.MapFS{
+	"greeting.py":        {Data: []byte("def greet(name):\n    return 'hello ' + name\n\ndef fail():\n    raise ValueError('failed')\n")},
+	"pkg/__init__.py":    {Data: []byte("from .sub import VALUE\n")},
+	"pkg/sub.py":         {Data: []byte("VALUE = 42\n")},
+	"pkg/data/info.txt":  {Data: []byte("ünï\n")},
+	"pkg/data/image.bin": {Data: []byte{0, 1, 2}},
+	"notpkg/orphan.py":   {Data: []byte("ORPHAN = True\n")},
+	"latin.py":           {Data: []byte("# -*- coding: latin-1 -*-\nVALUE = '\xe9'\n")},
+}
+
+// Adds the importer and removes it, as well as the modules it imported, when the test is done
+func addTestFSImporter(t *testing.T) *FSImporter {
+	t.Helper()
+
+	var importer *FSImporter
+	withGIL(t, func() {
+		var err error
+		if importer, err = AddFSImporter("test", testFS); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Cleanup(func() {
+		withGIL(t, func() {
+			if err := importer.Remove(); err != nil {
+				t.Error(err)
+			}
+			if err := Exec("import sys\nfor name in [name for name, module in sys.modules.items() if getattr(module, '__file__', '').startswith('test/')]:\n    del sys.modules[name]\n", nil, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	})
+
+	return importer
+}
+
+func importTest(t *testing.T, name string) *Reference {
+	t.Helper()
+
+	if module, err := Import(name); err == nil {
+		return module
+	} else {
+		t.Fatal(err)
+		return nil
+	}
+}
+
+func TestFSImporterModule(t *testing.T) {
+	CheckLeaks(t)
+	addTestFSImporter(t)
+
+	withGIL(t, func() {
+		module := importTest(t, "greeting")
+		defer module.Release()
+
+		assertPython(t, module, "value.greet('world') == 'hello world'")
+		assertPython(t, module, "value.__file__ == 'test/greeting.py' and value.__spec__.has_location")
+
+		// Source lines in tracebacks come from the loader
+		if fail, err := module.GetAttr("fail"); err == nil {
+			_, err := fail.Call()
+			fail.Release()
+
+			var exception *Exception
+			if errors.As(err, &exception) {
+				frames := exception.Frames()
+				if frame := frames[len(frames)-1]; (frame.Filename != "test/greeting.py") || (frame.Source != "raise ValueError('failed')") {
+					t.Errorf("unexpected frame %#v", frame)
+				}
+				exception.Release()
+			} else {
+				t.Errorf("expected an *Exception, got %v", err)
+			}
+		} else {
+			t.Error(err)
+		}
+
+		latin := importTest(t, "latin")
+		defer latin.Release()
+		assertPython(t, latin, "value.VALUE == '\\xe9'")
+	})
+}
+
+func TestFSImporterPackage(t *testing.T) {
+	CheckLeaks(t)
+	addTestFSImporter(t)
+
+	withGIL(t, func() {
+		module := importTest(t, "pkg")
+		defer module.Release()
+
+		assertPython(t, module, "value.VALUE == 42 and value.__file__ == 'test/pkg/__init__.py'")
+		assertPython(t, module, "value.__path__ == ['test/pkg'] and value.sub.__file__ == 'test/pkg/sub.py'")
+
+		// Not a package without "__init__.py"
+		if module, err := Import("notpkg.orphan"); err == nil {
+			module.Release()
+			t.Error("expected an error")
+		} else {
+			if !errors.Is(err, ErrModuleNotFoundError) {
+				t.Errorf("expected ModuleNotFoundError, got %v", err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+func TestFSImporterResources(t *testing.T) {
+	CheckLeaks(t)
+	addTestFSImporter(t)
+
+	withGIL(t, func() {
+		module := importTest(t, "pkg")
+		defer module.Release()
+
+		files := eval(t, "__import__('importlib.resources').resources.files(pkg)", map[string]interface{}{"pkg": module})
+		defer files.Release()
+
+		assertPython(t, files, "value.is_dir() and not value.is_file() and str(value) == 'test/pkg'")
+		assertPython(t, files, "sorted(child.name for child in value.iterdir()) == ['__init__.py', 'data', 'sub.py']")
+		assertPython(t, files, "(value / 'data' / 'info.txt').read_text(encoding='utf-8') == 'ünï\\n'")
+		assertPython(t, files, "value.joinpath('data/image.bin').read_bytes() == b'\\x00\\x01\\x02'")
+		assertPython(t, files, "value.joinpath('data', 'image.bin').open('rb').read() == b'\\x00\\x01\\x02'")
+		assertPython(t, files, "value.joinpath('data/info.txt').open(encoding='utf-8').read() == 'ünï\\n'")
+		assertPython(t, module, "value.__loader__.get_data('test/pkg/sub.py') == b'VALUE = 42\\n'")
+
+		for expression, value := range map[string]*Reference{
+			"value.joinpath('missing.txt').read_bytes()":        files,
+			"value.__loader__.get_data('elsewhere/pkg/sub.py')": module,
+		} {
+			globals := newGlobals(t, map[string]interface{}{"value": value})
+			if result, err := Eval(expression, globals, nil); err == nil {
+				result.Release()
+				t.Errorf("%s: expected an error", expression)
+			} else {
+				if !errors.Is(err, ErrFileNotFoundError) {
+					t.Errorf("%s: expected FileNotFoundError, got %v", expression, err)
+				}
+				releaseError(err)
+			}
+			globals.Release()
+		}
+	})
+}
+
+func TestFSImporterRemove(t *testing.T) {
+	CheckLeaks(t)
+	importer := addTestFSImporter(t)
+
+	withGIL(t, func() {
+		assertPython(t, importer.Finder, "value in __import__('sys').meta_path")
+
+		if err := importer.Remove(); err != nil {
+			t.Fatal(err)
+		}
+		if importer.Finder != nil {
+			t.Error("expected the finder to be nil")
+		}
+
+		// A no-op
+		if err := importer.Remove(); err != nil {
+			t.Error(err)
+		}
+
+		if module, err := Import("greeting"); err == nil {
+			module.Release()
+			t.Error("expected an error")
+		} else {
+			releaseError(err)
+		}
+	})
+}
+
+func TestPrependFSImporter(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		importer, err := PrependFSImporter("test", testFS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer importer.Remove()
+
+		assertPython(t, importer.Finder, "value is __import__('sys').meta_path[0]")
+	})
+}
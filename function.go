@@ -0,0 +1,334 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/structures.html
+//   https://docs.python.org/3/c-api/capsule.html
+
+// Note: cgo exports cannot be in the same file as cgo preamble functions,
+// which is why the Go side of the trampoline is in "export.go"
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/cgo"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+
+PyObject *py4go_callGoFunction(uintptr_t handle, PyObject *args, PyObject *kw);
+void py4go_releaseGoFunction(uintptr_t handle);
+
+typedef struct {
+	PyMethodDef def;
+	uintptr_t handle;
+} py4go_GoFunction;
+
+// PyCFunctionWithKeywords signature
+// The single trampoline for all Go functions, which are identified by the capsule in "self"
+static PyObject *py4go_goFunctionTrampoline(PyObject *self, PyObject *args, PyObject *kw) {
+	py4go_GoFunction *function = (py4go_GoFunction *) PyCapsule_GetPointer(self, NULL);
+	if (function == NULL)
+		return NULL;
+	return py4go_callGoFunction(function->handle, args, kw);
+}
+
+// PyCapsule_Destructor signature
+static void py4go_goFunctionDestructor(PyObject *capsule) {
+	py4go_GoFunction *function = (py4go_GoFunction *) PyCapsule_GetPointer(capsule, NULL);
+	if (function != NULL) {
+		py4go_releaseGoFunction(function->handle);
+		free((void *) function->def.ml_name);
+		free(function);
+	}
+}
+
+// Sets "owned" to 1 once the capsule owns the handle, after which the capsule destructor will
+// release it (even if creating the function fails)
+static PyObject *py4go_newGoFunction(char *name, uintptr_t handle, PyObject *module, int *owned) {
+	*owned = 0;
+
+	py4go_GoFunction *function = (py4go_GoFunction *) calloc(1, sizeof(py4go_GoFunction));
+	function->def.ml_name = name;
+	function->def.ml_meth = (PyCFunction) py4go_goFunctionTrampoline;
+	function->def.ml_flags = METH_VARARGS | METH_KEYWORDS;
+	function->handle = handle;
+
+	PyObject *capsule = PyCapsule_New(function, NULL, py4go_goFunctionDestructor);
+	if (capsule == NULL) {
+		free(name);
+		free(function);
+		return NULL;
+	}
+	*owned = 1;
+
+	// The function object keeps the capsule alive
+	PyObject *r = PyCFunction_NewEx(&function->def, capsule, module);
+	Py_DECREF(capsule);
+	return r;
+}
+*/
+import "C"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var argumentsType = reflect.TypeOf(Arguments{})
+
+//
+// Arguments
+//
+
+// A Go function whose last parameter is of this type receives in it the positional arguments that
+// remain after the other parameters as well as the keyword arguments, like Python's "*args" and
+// "**kwargs". Otherwise keyword arguments are not supported.
+//
+// The references are borrowed for the duration of the call.
+type Arguments struct {
+	Positional []*Reference
+	Keywords   map[string]*Reference
+}
+
+// Returns the positional argument at the index or else the keyword argument, or nil if neither was
+// given
+func (self Arguments) Get(index int, keyword string) *Reference {
+	if index < len(self.Positional) {
+		return self.Positional[index]
+	} else {
+		return self.Keywords[keyword]
+	}
+}
+
+//
+// GoFunction
+//
+
+type GoFunction struct {
+	Name  string
+	Value reflect.Value
+
+	receiver reflect.Type // for methods
+}
+
+// Wraps any Go func as a Python callable. Arguments are converted via Unmarshal and return values
+// via NewReferenceFromValue. If the last return value is an error then a non-nil error will be
+// raised as a Python exception (see RaiseGoError), after which an *Exception in the error is
+// released. Keyword arguments are only supported via Arguments.
+//
+// *Reference arguments (including those nested in slices, maps, and structs) are borrowed for the
+// duration of the call, so call Acquire on them if you want to keep them. A returned *Reference
+// is stolen, so Acquire it first if you want to keep it.
+func NewGoFunction(name string, function interface{}) (*Reference, error) {
+	return newGoFunction(name, function, nil)
+}
+
+func (self *Reference) AddModuleGoFunction(name string, function interface{}) error {
+	if moduleName := C.PyModule_GetNameObject(self.Object); moduleName != nil {
+		moduleName_ := NewReference(moduleName)
+		defer moduleName_.Release()
+
+		if function_, err := newGoFunction(name, function, moduleName_); err == nil {
+			defer function_.Release()
+			return self.SetAttr(name, function_)
+		} else {
+			return err
+		}
+	} else {
+		return GetError()
+	}
+}
+
+func newGoFunction(name string, function interface{}, module *Reference) (*Reference, error) {
+	value := reflect.ValueOf(function)
+	if value.Kind() != reflect.Func {
+		return nil, fmt.Errorf("not a function: %T", function)
+	}
+
+	return newGoFunctionFrom(&GoFunction{
+		Name:  name,
+		Value: value,
+	}, module)
+}
+
+// The first argument must be a Go object wrapping the receiver type
+func newGoMethod(name string, function reflect.Value, receiver reflect.Type) (*Reference, error) {
+	return newGoFunctionFrom(&GoFunction{
+		Name:     name,
+		Value:    function,
+		receiver: receiver,
+	}, nil)
+}
+
+func newGoFunctionFrom(function *GoFunction, module *Reference) (*Reference, error) {
+	name := function.Name
+	handle := cgo.NewHandle(function)
+
+	var module_ *C.PyObject
+	if module != nil {
+		module_ = module.Object
+	}
+
+	// The name will be freed by the capsule destructor
+	var owned C.int
+	if function_ := C.py4go_newGoFunction(C.CString(name), C.uintptr_t(handle), module_, &owned); function_ != nil {
+		return NewReference(function_), nil
+	} else {
+		if owned == 0 {
+			handle.Delete()
+		}
+		return nil, GetError()
+	}
+}
+
+func (self *GoFunction) Call(args *Reference, kw *Reference) (*Reference, error) {
+	type_ := self.Value.Type()
+
+	fixedCount := type_.NumIn()
+	hasArguments := (fixedCount > 0) && (type_.In(fixedCount-1) == argumentsType)
+
+	if (kw != nil) && !hasArguments {
+		if C.PyDict_Size(kw.Object) > 0 {
+			return nil, newTypeError("%s() takes no keyword arguments", self.Name)
+		}
+	}
+
+	args_, _ := getSequenceItems(args)
+	count := len(args_)
+
+	if (self.receiver != nil) && !self.isReceiver(args_) {
+		return nil, newTypeError("%s() requires a %s receiver", self.Name, self.receiver)
+	}
+
+	var arguments Arguments
+	if hasArguments {
+		fixedCount--
+		if count < fixedCount {
+			return nil, newTypeError("%s() takes at least %d arguments (%d given)", self.Name, fixedCount, count)
+		}
+
+		arguments.Positional = args_[fixedCount:]
+		args_ = args_[:fixedCount]
+
+		if kw != nil {
+			arguments.Keywords = make(map[string]*Reference)
+			for _, item := range getDictItems(kw) {
+				if name, err := item[0].ToString(); err == nil {
+					arguments.Keywords[name] = item[1]
+				} else {
+					return nil, err
+				}
+			}
+		}
+	} else if type_.IsVariadic() {
+		fixedCount--
+		if count < fixedCount {
+			return nil, newTypeError("%s() takes at least %d arguments (%d given)", self.Name, fixedCount, count)
+		}
+	} else if count != fixedCount {
+		return nil, newTypeError("%s() takes %d arguments (%d given)", self.Name, fixedCount, count)
+	}
+
+	// References created while unmarshaling are borrowed for the duration of the call, too
+	unmarshaler := unmarshaler{track: true}
+	defer unmarshaler.release()
+
+	in := make([]reflect.Value, len(args_))
+	for index, arg := range args_ {
+		var argType reflect.Type
+		if index < fixedCount {
+			argType = type_.In(index)
+		} else {
+			argType = type_.In(fixedCount).Elem()
+		}
+
+		if argType == referenceType {
+			// Borrowed from the args tuple
+			in[index] = reflect.ValueOf(arg)
+			continue
+		}
+
+		in[index] = reflect.New(argType).Elem()
+		if err := unmarshaler.unmarshal(arg, in[index], fmt.Sprintf("%s() argument %d", self.Name, index+1)); err != nil {
+			return nil, newTypeError("%s", err.Error())
+		}
+	}
+
+	if hasArguments {
+		in = append(in, reflect.ValueOf(arguments))
+	}
+
+	out := self.Value.Call(in)
+	defer unmarshaler.releaseReturned(out)
+
+	if length := len(out); (length > 0) && (type_.Out(length-1) == errorType) {
+		if err, _ := out[length-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:length-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return newAcquiredReference(C.Py_None), nil
+
+	case 1:
+		return newReferenceFromValue(out[0])
+
+	default:
+		values := make([]interface{}, len(out))
+		for index, value := range out {
+			values[index] = value.Interface()
+		}
+		return newTupleFromValue(reflect.ValueOf(values))
+	}
+}
+
+func (self *GoFunction) isReceiver(args []*Reference) bool {
+	if len(args) > 0 {
+		if value, ok := args[0].getGoObjectValue(); ok {
+			return value.Type() == self.receiver
+		}
+	}
+	return false
+}
+
+//
+// typeError
+//
+
+// Raised as a Python TypeError
+type typeError struct {
+	message string
+}
+
+func newTypeError(format string, args ...interface{}) *typeError {
+	return &typeError{fmt.Sprintf(format, args...)}
+}
+
+// error signature
+func (self *typeError) Error() string {
+	return self.message
+}
+
+// The converted results hold their own references. Returned arguments are left to the unmarshaler.
+func (self *unmarshaler) releaseReturned(out []reflect.Value) {
+	for _, value := range out {
+		if (value.Kind() == reflect.Interface) && !value.IsNil() {
+			value = value.Elem()
+		}
+
+		if (value.Type() == referenceType) && !value.IsNil() {
+			if reference := value.Interface().(*Reference); !self.isTracked(reference) {
+				reference.Release()
+			}
+		}
+	}
+}
+
+func (self *unmarshaler) isTracked(reference *Reference) bool {
+	for _, reference_ := range self.acquired {
+		if reference_ == reference {
+			return true
+		}
+	}
+	return false
+}
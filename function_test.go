@@ -0,0 +1,231 @@
+package python
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestGoFunction(t *testing.T, name string, function interface{}) *Reference {
+	t.Helper()
+
+	if function_, err := NewGoFunction(name, function); err == nil {
+		return function_
+	} else {
+		t.Fatal(err)
+		return nil
+	}
+}
+
+// Calls the function from Python via the expression, with the function bound to "f"
+func callGoFunction(t *testing.T, function *Reference, expression string) (*Reference, error) {
+	t.Helper()
+
+	globals := newGlobals(t, map[string]interface{}{"f": function})
+	defer globals.Release()
+
+	return Eval(expression, globals, nil)
+}
+
+func TestGoFunctionConversion(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		function := newTestGoFunction(t, "describe", func(name string, count int, tags []string, options map[string]bool) string {
+			return fmt.Sprintf("%s %d %s %v", name, count, strings.Join(tags, ","), options["verbose"])
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "f('x', 3, ['a', 'b'], {'verbose': True})"); err == nil {
+			assertPython(t, result, "value == 'x 3 a,b true'")
+			result.Release()
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestGoFunctionReturnValues(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, test := range []struct {
+			function   interface{}
+			expression string
+		}{
+			{func() {}, "value is None"},
+			{func() error { return nil }, "value is None"},
+			{func() (int, error) { return 1, nil }, "value == 1"},
+			{func() (string, int) { return "a", 2 }, "value == ('a', 2)"},
+			{func() []testItem { return []testItem{{"a", 1}} }, "value == [{'Name': 'a', 'Count': 1}]"},
+			{func() *testItem { return nil }, "value is None"},
+		} {
+			function := newTestGoFunction(t, "f", test.function)
+			if result, err := callGoFunction(t, function, "f()"); err == nil {
+				assertPython(t, result, test.expression)
+				result.Release()
+			} else {
+				t.Errorf("%s: %s", test.expression, err)
+			}
+			function.Release()
+		}
+	})
+}
+
+func TestGoFunctionVariadic(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		function := newTestGoFunction(t, "sum", func(base int, values ...int) int {
+			for _, value := range values {
+				base += value
+			}
+			return base
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "(f(1), f(1, 2, 3))"); err == nil {
+			assertPython(t, result, "value == (1, 6)")
+			result.Release()
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestGoFunctionArguments(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		function := newTestGoFunction(t, "describe", func(name string, arguments Arguments) (string, error) {
+			var count int
+			if count_ := arguments.Get(0, "count"); count_ != nil {
+				if err := count_.Unmarshal(&count); err != nil {
+					return "", err
+				}
+			}
+			return fmt.Sprintf("%s %d %d %d", name, count, len(arguments.Positional), len(arguments.Keywords)), nil
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "(f('a'), f('b', 2, 3), f('c', count=4, other=5))"); err == nil {
+			assertPython(t, result, "value == ('a 0 0 0', 'b 2 2 0', 'c 4 0 2')")
+			result.Release()
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestGoFunctionReference(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		function := newTestGoFunction(t, "identity", func(reference *Reference) *Reference {
+			// The returned reference is stolen
+			reference.Acquire()
+			return reference
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "(lambda o: f(o) is o)(object())"); err == nil {
+			assertPython(t, result, "value is True")
+			result.Release()
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestGoFunctionErrors(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		function := newTestGoFunction(t, "check", func(value int) error {
+			if value < 0 {
+				return errors.New("negative")
+			}
+			return nil
+		})
+		defer function.Release()
+
+		for _, test := range []struct {
+			expression string
+			target     error
+			message    string
+		}{
+			{"f(-1)", ErrRuntimeError, "negative"},
+			{"f()", ErrTypeError, "check() takes 1 arguments (0 given)"},
+			{"f('x')", ErrTypeError, "check() argument 1: expected int, got str"},
+			{"f(value=1)", ErrTypeError, "check() takes no keyword arguments"},
+		} {
+			if result, err := callGoFunction(t, function, test.expression); err == nil {
+				result.Release()
+				t.Errorf("%s: expected an error", test.expression)
+			} else {
+				if !errors.Is(err, test.target) {
+					t.Errorf("%s: expected %s, got %s", test.expression, test.target, err)
+				}
+				if !strings.Contains(err.Error(), test.message) {
+					t.Errorf("%s: expected %q in %q", test.expression, test.message, err.Error())
+				}
+				releaseError(err)
+			}
+		}
+	})
+}
+
+func TestGoFunctionPanic(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		function := newTestGoFunction(t, "fail", func() {
+			panic("oops")
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "f()"); err == nil {
+			result.Release()
+			t.Error("expected an error")
+		} else {
+			if !errors.Is(err, ErrRuntimeError) || !strings.Contains(err.Error(), "Go panic: oops") {
+				t.Errorf("unexpected error %s", err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+func TestNewGoFunctionNotAFunction(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		if function, err := NewGoFunction("f", 1); err == nil {
+			function.Release()
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestGoFunctionReturnsException(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		// The *Exception is released after it is raised
+		function := newTestGoFunction(t, "fail", func() error {
+			return Exec("raise KeyError('inner')", nil, nil)
+		})
+		defer function.Release()
+
+		if result, err := callGoFunction(t, function, "f()"); err == nil {
+			result.Release()
+			t.Error("expected an error")
+		} else {
+			if !errors.Is(err, ErrKeyError) {
+				t.Errorf("expected KeyError, got %s", err)
+			}
+			releaseError(err)
+		}
+	})
+}
@@ -9,11 +9,21 @@ package python
 */
 import "C"
 
-func Initialize() {
-	C.Py_Initialize()
+// Returns an error if a virtual environment is used (see UseVirtualEnv) and initialization fails.
+// Otherwise failure aborts the process. Use InitializeWithConfig to always get an error instead.
+func Initialize() error {
+	if getDefaultVirtualEnv() != nil {
+		return InitializeWithConfig(Config{})
+	} else {
+		C.Py_Initialize()
+		return nil
+	}
 }
 
 func Finalize() error {
+	DisableFinalizers()
+	releaseInterpreterCache()
+
 	if C.Py_FinalizeEx() == 0 {
 		return nil
 	} else {
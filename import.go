@@ -4,6 +4,7 @@ package python
 //   https://docs.python.org/3/c-api/import.html
 
 import (
+	"reflect"
 	"unsafe"
 )
 
@@ -23,3 +24,35 @@ func Import(name string) (*Reference, error) {
 		return nil, GetError()
 	}
 }
+
+// Imports the module and calls the attribute. The arguments and keyword arguments (which can be
+// nil) are converted via NewReferenceFromValue.
+func callModuleAttr(module string, name string, args []interface{}, kw map[string]interface{}) (*Reference, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	module_, err := scope.Track(Import(module))
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := scope.Track(module_.GetAttr(name))
+	if err != nil {
+		return nil, err
+	}
+
+	args_, err := scope.Track(newTupleFromValue(reflect.ValueOf(args)))
+	if err != nil {
+		return nil, err
+	}
+
+	if kw == nil {
+		kw = make(map[string]interface{})
+	}
+	kw_, err := scope.Track(NewReferenceFromValue(kw))
+	if err != nil {
+		return nil, err
+	}
+
+	return attr.CallRaw(args_, kw_)
+}
@@ -0,0 +1,354 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/init.html#sub-interpreter-support
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+
+static PyThreadState *py4go_getCurrentThreadState() {
+#if PY_VERSION_HEX >= 0x030D0000
+	return PyThreadState_GetUnchecked();
+#else
+	return _PyThreadState_UncheckedGet();
+#endif
+}
+
+// Must be called while holding the GIL. Returns the new interpreter's initial thread state, or NULL
+// and sets the error message on failure.
+static PyThreadState *py4go_newInterpreter(int ownGil, const char **error) {
+	PyThreadState *previous = py4go_getCurrentThreadState();
+	PyThreadState *state = NULL;
+
+#if PY_VERSION_HEX >= 0x030C0000
+	PyInterpreterConfig config = {
+		.use_main_obmalloc = 1,
+		.allow_fork = 1,
+		.allow_exec = 1,
+		.allow_threads = 1,
+		.allow_daemon_threads = 1,
+		.check_multi_interp_extensions = 0,
+		.gil = PyInterpreterConfig_SHARED_GIL,
+	};
+	if (ownGil) {
+		config.use_main_obmalloc = 0;
+		config.allow_fork = 0;
+		config.allow_exec = 0;
+		config.allow_daemon_threads = 0;
+		config.check_multi_interp_extensions = 1;
+		config.gil = PyInterpreterConfig_OWN_GIL;
+	}
+	PyStatus status = Py_NewInterpreterFromConfig(&state, &config);
+	if (PyStatus_Exception(status)) {
+		*error = status.err_msg != NULL ? status.err_msg : "could not create interpreter";
+		return NULL;
+	}
+#else
+	if (ownGil) {
+		*error = "a per-interpreter GIL requires Python 3.12 or later";
+		return NULL;
+	}
+	state = Py_NewInterpreter();
+	if (state == NULL) {
+		*error = "could not create interpreter";
+		return NULL;
+	}
+#endif
+
+	// The new thread state is current and we are holding the new interpreter's GIL. We keep the
+	// initial thread state for Py_EndInterpreter, because (at least in Python 3.11) it cannot be
+	// deleted and recreated.
+	PyEval_SaveThread();
+	PyEval_RestoreThread(previous);
+	return state;
+}
+
+// Switches the current thread to a new thread state of the interpreter, releasing the GIL we are
+// holding (if any) and acquiring the interpreter's GIL. Returns the previous thread state.
+static PyThreadState *py4go_enterInterpreter(PyInterpreterState *interpreter) {
+	PyThreadState *previous = py4go_getCurrentThreadState();
+	if (previous != NULL)
+		PyEval_SaveThread();
+	PyEval_RestoreThread(PyThreadState_New(interpreter));
+	return previous;
+}
+
+static void py4go_exitInterpreter(PyThreadState *previous) {
+	PyThreadState_Clear(PyThreadState_Get());
+	PyThreadState_DeleteCurrent();
+	if (previous != NULL)
+		PyEval_RestoreThread(previous);
+}
+
+// The previous thread state must not be NULL
+static void py4go_endInterpreter(PyThreadState *state, PyThreadState *previous) {
+	PyEval_SaveThread();
+	PyEval_RestoreThread(state);
+	Py_EndInterpreter(state);
+#if PY_VERSION_HEX >= 0x030C0000
+	// No GIL is held
+	PyEval_RestoreThread(previous);
+#else
+	// The (shared) GIL is still held
+	PyThreadState_Swap(previous);
+#endif
+}
+*/
+import "C"
+
+var ErrInterpreterClosed = errors.New("interpreter is closed")
+var ErrWrongInterpreter = errors.New("reference belongs to a different interpreter")
+
+// Objects we create on demand, such as exception types and helper modules, belong to the
+// interpreter in which they were created, so we keep them per interpreter
+var interpreterCaches = make(map[*C.PyInterpreterState]map[string]*Reference)
+var interpreterCachesLock sync.Mutex
+
+// The open interpreters by state, so that we can find the Interpreter of the current thread
+var interpreters = make(map[*C.PyInterpreterState]*Interpreter)
+var interpreterLock sync.Mutex
+
+//
+// Interpreter
+//
+
+// A sub-interpreter, with its own sys.modules, builtins, and __main__ module, isolated from the
+// main interpreter and from other sub-interpreters.
+//
+// References created inside Do belong to the interpreter and must only be used inside Do. Most
+// Reference methods return ErrWrongInterpreter if you try to use them elsewhere.
+//
+// Interpreters should be closed before calling Finalize.
+type Interpreter struct {
+	state        *C.PyInterpreterState
+	initialState *C.PyThreadState
+	lock         sync.RWMutex
+}
+
+// Must be called while holding the GIL of the main interpreter, which is still held afterwards.
+func NewInterpreter() (*Interpreter, error) {
+	return newInterpreter(false)
+}
+
+// Like NewInterpreter but the interpreter has its own GIL, so it can run in parallel with other
+// interpreters. Requires Python 3.12 or later. Note that many extension modules do not support
+// such interpreters.
+func NewInterpreterWithOwnGIL() (*Interpreter, error) {
+	return newInterpreter(true)
+}
+
+func newInterpreter(ownGil bool) (*Interpreter, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if C.py4go_getCurrentThreadState() == nil {
+		return nil, errors.New("creating an interpreter requires holding the GIL")
+	}
+
+	ownGil_ := C.int(0)
+	if ownGil {
+		ownGil_ = 1
+	}
+
+	var error_ *C.char
+	if initialState := C.py4go_newInterpreter(ownGil_, &error_); initialState != nil {
+		self := &Interpreter{
+			state:        C.PyThreadState_GetInterpreter(initialState),
+			initialState: initialState,
+		}
+
+		interpreterLock.Lock()
+		interpreters[self.state] = self
+		interpreterLock.Unlock()
+
+		return self, nil
+	} else {
+		return nil, errors.New(C.GoString(error_))
+	}
+}
+
+// Runs the function in the interpreter, on the current OS thread and while holding the
+// interpreter's GIL. If the current thread is holding another GIL it is released for the
+// duration.
+func (self *Interpreter) Do(f func() error) error {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if self.state == nil {
+		return ErrInterpreterClosed
+	}
+
+	return self.do(f)
+}
+
+func (self *Interpreter) do(f func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	previous := C.py4go_enterInterpreter(self.state)
+	defer C.py4go_exitInterpreter(previous)
+
+	return f()
+}
+
+// Imports a module in the interpreter. The module belongs to the interpreter.
+func (self *Interpreter) Import(name string) (*Reference, error) {
+	var module *Reference
+	err := self.Do(func() error {
+		var err error
+		module, err = Import(name)
+		return err
+	})
+	return module, err
+}
+
+// Executes Python code in the interpreter's __main__ module
+func (self *Interpreter) Run(code string) error {
+	return self.Do(func() error {
+		main_ := C.CString("__main__")
+		defer C.free(unsafe.Pointer(main_))
+
+		// Borrowed references
+		if main := C.PyImport_AddModule(main_); main != nil {
+			return Exec(code, NewBorrowedReference(C.PyModule_GetDict(main)), nil)
+		} else {
+			return GetError()
+		}
+	})
+}
+
+// Destroys the interpreter. All its references become invalid.
+//
+// Must be called while holding the GIL of the main interpreter, which is still held afterwards.
+func (self *Interpreter) Close() error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if C.py4go_getCurrentThreadState() == nil {
+		return errors.New("closing an interpreter requires holding the GIL")
+	}
+
+	// Other threads might be waiting for the GIL inside Do, so we must not hold it while waiting
+	// for them
+	threadState := SaveThreadState()
+	self.lock.Lock()
+	threadState.Restore()
+	defer self.lock.Unlock()
+
+	if self.state == nil {
+		return nil
+	}
+
+	self.do(func() error {
+		releaseInterpreterCache()
+		return nil
+	})
+
+	interpreterLock.Lock()
+	delete(interpreters, self.state)
+	interpreterLock.Unlock()
+
+	C.py4go_endInterpreter(self.initialState, C.py4go_getCurrentThreadState())
+	self.state = nil
+	self.initialState = nil
+
+	return nil
+}
+
+// Whether the current thread is running in this interpreter
+func (self *Interpreter) IsCurrent() bool {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	return (self.state != nil) && (getCurrentInterpreter() == self.state)
+}
+
+// Returns the Interpreter the current thread is running in, or nil for the main interpreter (or if
+// there is no current thread state)
+func getCurrentSubInterpreter() *Interpreter {
+	if state := getCurrentInterpreter(); state != nil {
+		interpreterLock.Lock()
+		defer interpreterLock.Unlock()
+
+		return interpreters[state]
+	}
+	return nil
+}
+
+// Runs the function with Do, or with WithGIL if the interpreter is nil (the main interpreter)
+func withInterpreterGIL(interpreter *Interpreter, f func() error) error {
+	if interpreter != nil {
+		return interpreter.Do(f)
+	} else {
+		return WithGIL(f)
+	}
+}
+
+// Returns the object cached for the current interpreter under the key, calling create to create
+// it if necessary. The returned reference is borrowed from the cache.
+//
+// Must be called while holding the GIL.
+func getInterpreterCached(key string, create func() (*Reference, error)) (*Reference, error) {
+	interpreter := getCurrentInterpreter()
+
+	interpreterCachesLock.Lock()
+	reference, ok := interpreterCaches[interpreter][key]
+	interpreterCachesLock.Unlock()
+
+	if ok {
+		return reference, nil
+	}
+
+	// Creating may run Python code, so we must not hold the lock
+	if reference, err := create(); err == nil {
+		interpreterCachesLock.Lock()
+		defer interpreterCachesLock.Unlock()
+
+		cache, ok := interpreterCaches[interpreter]
+		if !ok {
+			cache = make(map[string]*Reference)
+			interpreterCaches[interpreter] = cache
+		}
+
+		if reference_, ok := cache[key]; ok {
+			// Another thread created it while create released the GIL
+			reference.Release()
+			return reference_, nil
+		}
+
+		// Cached for the lifetime of the interpreter, so not a leak
+		reference.untrack()
+
+		cache[key] = reference
+		return reference, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Releases the objects cached for the current interpreter. Called before the interpreter is
+// finalized.
+//
+// Must be called while holding the GIL.
+func releaseInterpreterCache() {
+	interpreter := getCurrentInterpreter()
+
+	interpreterCachesLock.Lock()
+	cache := interpreterCaches[interpreter]
+	delete(interpreterCaches, interpreter)
+	interpreterCachesLock.Unlock()
+
+	for _, reference := range cache {
+		// Does nothing if it is not a helper class
+		unregisterHelperGoClass(reference.Object)
+		reference.Release()
+	}
+}
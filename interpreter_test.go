@@ -0,0 +1,145 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestInterpreter(t *testing.T) *Interpreter {
+	t.Helper()
+
+	if interpreter, err := NewInterpreter(); err == nil {
+		return interpreter
+	} else {
+		t.Fatal(err)
+		return nil
+	}
+}
+
+func TestInterpreter(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		interpreter := newTestInterpreter(t)
+		defer interpreter.Close()
+
+		if interpreter.IsCurrent() {
+			t.Error("expected the interpreter not to be current")
+		}
+
+		if err := interpreter.Run("import sys\nsys.py4go_test = 'sub'\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := interpreter.Do(func() error {
+			if !interpreter.IsCurrent() {
+				t.Error("expected the interpreter to be current")
+			}
+			assertPython(t, None, "__import__('sys').py4go_test == 'sub'")
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+
+		// Isolated from the main interpreter
+		assertPython(t, None, "not hasattr(__import__('sys'), 'py4go_test')")
+
+		// Errors are returned from Do
+		expected := errors.New("expected")
+		if err := interpreter.Do(func() error { return expected }); err != expected {
+			t.Errorf("expected the function's error, got %v", err)
+		}
+
+		if err := interpreter.Run("raise ValueError('sub')"); err == nil {
+			t.Error("expected an error")
+		} else {
+			if !errors.Is(err, ErrValueError) {
+				t.Errorf("expected ValueError, got %v", err)
+			}
+			interpreter.Do(func() error {
+				releaseError(err)
+				return nil
+			})
+		}
+	})
+}
+
+func TestInterpreterWrongInterpreter(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		interpreter := newTestInterpreter(t)
+		defer interpreter.Close()
+
+		module, err := interpreter.Import("json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Used outside of the interpreter
+		if _, err := module.GetAttr("dumps"); err != ErrWrongInterpreter {
+			t.Errorf("expected ErrWrongInterpreter, got %v", err)
+		}
+
+		function := eval(t, "len", nil)
+		defer function.Release()
+		if _, err := function.Call(module); err != ErrWrongInterpreter {
+			t.Errorf("expected ErrWrongInterpreter, got %v", err)
+		}
+
+		interpreter.Do(func() error {
+			if dumps, err := module.GetAttr("dumps"); err == nil {
+				dumps.Release()
+			} else {
+				t.Error(err)
+			}
+			module.Release()
+			return nil
+		})
+	})
+}
+
+func TestInterpreterClose(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		interpreter := newTestInterpreter(t)
+
+		// Creates cached objects, which are released on close
+		if err := interpreter.Do(func() error {
+			function := eval(t, "lambda: 1", nil)
+			defer function.Release()
+
+			if result, err := function.CallContext(context.Background()); err == nil {
+				result.Release()
+			} else {
+				t.Error(err)
+			}
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := interpreter.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := interpreter.Do(func() error { return nil }); err != ErrInterpreterClosed {
+			t.Errorf("expected ErrInterpreterClosed, got %v", err)
+		}
+		if interpreter.IsCurrent() {
+			t.Error("expected a closed interpreter not to be current")
+		}
+
+		// Repeated closes are harmless
+		if err := interpreter.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Requires the GIL
+	if _, err := NewInterpreter(); err == nil {
+		t.Error("expected an error without the GIL")
+	}
+}
@@ -0,0 +1,302 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/iter.html
+//   https://docs.python.org/3/library/stdtypes.html#iterator-types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+func (self *Reference) IsIterator() bool {
+	return C.PyIter_Check(self.Object) != 0
+}
+
+// Like Python's iter(). Works for any iterable, including lists, dicts, and generators.
+func (self *Reference) Iter() (*Iterator, error) {
+	if iterator := C.PyObject_GetIter(self.Object); iterator != nil {
+		return &Iterator{Iterator: NewReference(iterator)}, nil
+	} else {
+		return nil, GetError()
+	}
+}
+
+// Streams the items into the returned channel from a new goroutine, which acquires the GIL for
+// each item. You must release the items.
+//
+// The error channel receives an error if the iteration fails or the context is done, and is
+// closed after the items channel is closed.
+//
+// The GIL must not be held while receiving from the channels (see SaveThreadState), otherwise the
+// goroutine will not be able to acquire it. If called inside Interpreter.Do, the goroutine runs in
+// the same interpreter (via Do), and the error channel receives ErrInterpreterClosed if the
+// interpreter is closed before the iteration is done.
+func (self *Reference) Chan(context_ context.Context) (<-chan *Reference, <-chan error) {
+	items := make(chan *Reference)
+	errs := make(chan error, 1)
+
+	iterator, err := self.Iter()
+	if err != nil {
+		close(items)
+		errs <- err
+		close(errs)
+		return items, errs
+	}
+
+	// Nil for the main interpreter
+	interpreter := getCurrentSubInterpreter()
+
+	go func() {
+		defer close(errs)
+		defer close(items)
+
+		// If the interpreter has been closed then the iterator is already gone
+		defer withInterpreterGIL(interpreter, func() error {
+			iterator.Release()
+			return nil
+		})
+
+		for {
+			if err := context_.Err(); err != nil {
+				errs <- err
+				return
+			}
+
+			var item *Reference
+			if err := withInterpreterGIL(interpreter, func() error {
+				var err error
+				item, err = getNextItem(iterator.Iterator)
+				return err
+			}); err != nil {
+				errs <- err
+				return
+			} else if item == nil {
+				return
+			}
+
+			select {
+			case items <- item:
+			case <-context_.Done():
+				withInterpreterGIL(interpreter, func() error {
+					item.Release()
+					return nil
+				})
+				errs <- context_.Err()
+				return
+			}
+		}
+	}()
+
+	return items, errs
+}
+
+//
+// Iterator
+//
+
+// A cursor over a Python iterator, e.g.:
+//
+//	iterator, _ := list.Iter()
+//	defer iterator.Release()
+//	for iterator.Next() {
+//		item := iterator.Item()
+//	}
+//	if err := iterator.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	Iterator *Reference
+
+	item *Reference
+	err  error
+}
+
+// Advances to the next item. Returns false when the iteration is done or has failed (see Err).
+//
+// The previous item is released, so call Acquire on it if you want to keep it.
+func (self *Iterator) Next() bool {
+	self.releaseItem()
+
+	if (self.Iterator == nil) || (self.err != nil) {
+		return false
+	}
+
+	self.item, self.err = getNextItem(self.Iterator)
+	return self.item != nil
+}
+
+// The current item, which belongs to the iterator
+func (self *Iterator) Item() *Reference {
+	return self.item
+}
+
+// The error that ended the iteration, if there was one
+func (self *Iterator) Err() error {
+	return self.err
+}
+
+// Releases the iterator and the current item
+func (self *Iterator) Release() {
+	self.releaseItem()
+
+	if self.Iterator != nil {
+		self.Iterator.Release()
+		self.Iterator = nil
+	}
+}
+
+func (self *Iterator) releaseItem() {
+	if self.item != nil {
+		self.item.Release()
+		self.item = nil
+	}
+}
+
+// Returns nil without an error when the iterator is exhausted
+func getNextItem(iterator *Reference) (*Reference, error) {
+	if item := C.PyIter_Next(iterator.Object); item != nil {
+		return NewReference(item), nil
+	} else if HasException() {
+		return nil, GetError()
+	} else {
+		return nil, nil
+	}
+}
+
+//
+// Go iterators
+//
+
+// Creates a Python iterator that receives its items from the channel until it is closed. Items are
+// converted via NewReferenceFromValue.
+//
+// The GIL is released while waiting to receive.
+func NewChanIterator(channel interface{}) (*Reference, error) {
+	value := reflect.ValueOf(channel)
+	if (value.Kind() != reflect.Chan) || (value.Type().ChanDir()&reflect.RecvDir == 0) {
+		return nil, fmt.Errorf("not a receivable channel: %T", channel)
+	}
+
+	return newGoIterator(func() (interface{}, error) {
+		threadState := SaveThreadState()
+		item, ok := value.Recv()
+		threadState.Restore()
+
+		if ok {
+			return item.Interface(), nil
+		} else {
+			return nil, ErrStopIteration
+		}
+	}, nil)
+}
+
+// Creates a Python iterator that runs the function in its own goroutine, which produces items by
+// calling yield. The function is not started until the first item is requested, and each item is
+// only produced when requested. Yield returns false if the iterator is closed or garbage
+// collected, in which case the function should return.
+//
+// The GIL is released while waiting for an item.
+func newYieldIterator(run func(yield func(interface{}) bool)) (*Reference, error) {
+	requests := make(chan struct{})
+	items := make(chan interface{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if _, ok := <-requests; !ok {
+			return
+		}
+
+		run(func(item interface{}) bool {
+			items <- item
+			_, ok := <-requests
+			return ok
+		})
+	}()
+
+	return newGoIterator(func() (interface{}, error) {
+		threadState := SaveThreadState()
+		defer threadState.Restore()
+
+		select {
+		case requests <- struct{}{}:
+			select {
+			case item := <-items:
+				return item, nil
+			case <-done:
+			}
+		case <-done:
+		}
+
+		return nil, ErrStopIteration
+	}, func() {
+		close(requests)
+	})
+}
+
+// Creates a Python iterator that calls next for each item, which should return ErrStopIteration
+// when there are no more items. Close, if not nil, is called when the iterator is exhausted,
+// closed, or garbage collected.
+func newGoIterator(next func() (interface{}, error), close func()) (*Reference, error) {
+	if class, err := getHelperGoClass("py4go.Iterator", (*goIterator)(nil), map[string]interface{}{
+		"__iter__": func(self *Reference) *Reference {
+			// The returned reference is stolen
+			return newAcquiredReference(self.Object)
+		},
+		"__next__": (*goIterator).next_,
+		"__del__":  (*goIterator).Close,
+	}); err == nil {
+		return class.NewObject(&goIterator{
+			next:  next,
+			close: close,
+		})
+	} else {
+		return nil, err
+	}
+}
+
+//
+// goIterator
+//
+
+type goIterator struct {
+	next  func() (interface{}, error)
+	close func()
+	done  bool
+}
+
+// fmt.Stringer interface
+func (self *goIterator) String() string {
+	return "<py4go.Iterator>"
+}
+
+func (self *goIterator) next_() (interface{}, error) {
+	if self.done {
+		return nil, ErrStopIteration
+	}
+
+	item, err := self.next()
+	if errors.Is(err, ErrStopIteration) {
+		self.Close()
+	}
+	return item, err
+}
+
+func (self *goIterator) Close() {
+	if !self.done {
+		self.done = true
+		if self.close != nil {
+			self.close()
+		}
+	}
+}
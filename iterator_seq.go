@@ -0,0 +1,51 @@
+//go:build go1.23
+// +build go1.23
+
+package python
+
+// See:
+//   https://pkg.go.dev/iter
+
+import (
+	"iter"
+)
+
+// Iterates like Python's "for" statement. An error ends the iteration and is yielded with a nil
+// item.
+//
+// The items belong to the iteration, so call Acquire on an item if you want to keep it after the
+// loop body.
+func (self *Reference) All() iter.Seq2[*Reference, error] {
+	return func(yield func(*Reference, error) bool) {
+		iterator, err := self.Iter()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer iterator.Release()
+
+		for iterator.Next() {
+			if !yield(iterator.Item(), nil) {
+				return
+			}
+		}
+
+		if err := iterator.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Creates a Python iterator that pulls its items from the sequence. Items are converted via
+// NewReferenceFromValue.
+//
+// The sequence runs in its own goroutine, so it must use WithGIL if it needs to access Python.
+func NewSeqIterator[T any](seq iter.Seq[T]) (*Reference, error) {
+	return newYieldIterator(func(yield func(interface{}) bool) {
+		for item := range seq {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
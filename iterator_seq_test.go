@@ -0,0 +1,65 @@
+//go:build go1.23
+// +build go1.23
+
+package python
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		generator := eval(t, "(1 // x for x in (1, 1, 0))", nil)
+		defer generator.Release()
+
+		var values []int64
+		var err error
+		for item, err_ := range generator.All() {
+			if err_ != nil {
+				err = err_
+			} else if value, err_ := item.ToInt64(); err_ == nil {
+				values = append(values, value)
+			} else {
+				t.Error(err_)
+			}
+		}
+
+		if !errors.Is(err, ErrZeroDivisionError) {
+			t.Errorf("expected ZeroDivisionError, got %v", err)
+		}
+		releaseError(err)
+		assertInt64s(t, values, 1, 1)
+
+		// Breaking out of the loop
+		list := eval(t, "[1, 2, 3]", nil)
+		defer list.Release()
+		for range list.All() {
+			break
+		}
+	})
+}
+
+func TestNewSeqIterator(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		if iterator, err := NewSeqIterator(slices.Values([]string{"a", "b"})); err == nil {
+			defer iterator.Release()
+			assertPython(t, iterator, "list(value) == ['a', 'b']")
+		} else {
+			t.Fatal(err)
+		}
+
+		// Not exhausted
+		if iterator, err := NewSeqIterator(slices.Values([]int{1, 2, 3})); err == nil {
+			defer iterator.Release()
+			assertPython(t, iterator, "next(value) == 1")
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Receives all the items and the error without holding the GIL. Must be called while holding the
+// GIL, which is still held afterwards.
+func receiveChan(items <-chan *Reference, errs <-chan error) ([]*Reference, error) {
+	threadState := SaveThreadState()
+	defer threadState.Restore()
+
+	var items_ []*Reference
+	for item := range items {
+		items_ = append(items_, item)
+	}
+	return items_, <-errs
+}
+
+// Releases the items and returns them as int64s
+func toInt64s(t *testing.T, items []*Reference) []int64 {
+	t.Helper()
+
+	var values []int64
+	for _, item := range items {
+		if value, err := item.ToInt64(); err == nil {
+			values = append(values, value)
+		} else {
+			t.Error(err)
+		}
+		item.Release()
+	}
+	return values
+}
+
+func assertInt64s(t *testing.T, values []int64, expected ...int64) {
+	t.Helper()
+
+	if len(values) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+	for index, value := range values {
+		if value != expected[index] {
+			t.Fatalf("expected %v, got %v", expected, values)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		list := eval(t, "[1, 2, 3]", nil)
+		defer list.Release()
+
+		if iterator, err := list.Iter(); err == nil {
+			defer iterator.Release()
+
+			var values []int64
+			for iterator.Next() {
+				if value, err := iterator.Item().ToInt64(); err == nil {
+					values = append(values, value)
+				} else {
+					t.Error(err)
+				}
+			}
+			if err := iterator.Err(); err != nil {
+				t.Error(err)
+			}
+			assertInt64s(t, values, 1, 2, 3)
+
+			// Exhausted
+			if iterator.Next() {
+				t.Error("expected no more items")
+			}
+		} else {
+			t.Fatal(err)
+		}
+
+		// Not iterable
+		number := eval(t, "1", nil)
+		defer number.Release()
+		if _, err := number.Iter(); !errors.Is(err, ErrTypeError) {
+			t.Errorf("expected TypeError, got %v", err)
+		} else {
+			releaseError(err)
+		}
+	})
+}
+
+func TestIteratorError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		generator := eval(t, "(1 // x for x in (1, 0))", nil)
+		defer generator.Release()
+
+		if iterator, err := generator.Iter(); err == nil {
+			defer iterator.Release()
+
+			count := 0
+			for iterator.Next() {
+				count++
+			}
+			if count != 1 {
+				t.Errorf("expected 1 item, got %d", count)
+			}
+			if err := iterator.Err(); !errors.Is(err, ErrZeroDivisionError) {
+				t.Errorf("expected ZeroDivisionError, got %v", err)
+			}
+			releaseError(iterator.Err())
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestChan(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		list := eval(t, "[1, 2, 3]", nil)
+		defer list.Release()
+
+		items, err := receiveChan(list.Chan(context.Background()))
+		if err != nil {
+			t.Error(err)
+		}
+		assertInt64s(t, toInt64s(t, items), 1, 2, 3)
+	})
+}
+
+func TestChanError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		generator := eval(t, "(1 // x for x in (1, 0))", nil)
+		defer generator.Release()
+
+		items, err := receiveChan(generator.Chan(context.Background()))
+		if !errors.Is(err, ErrZeroDivisionError) {
+			t.Errorf("expected ZeroDivisionError, got %v", err)
+		}
+		releaseError(err)
+		assertInt64s(t, toInt64s(t, items), 1)
+
+		// Not iterable
+		number := eval(t, "1", nil)
+		defer number.Release()
+
+		items, err = receiveChan(number.Chan(context.Background()))
+		if !errors.Is(err, ErrTypeError) {
+			t.Errorf("expected TypeError, got %v", err)
+		}
+		releaseError(err)
+		if len(items) != 0 {
+			t.Errorf("expected no items, got %d", len(items))
+		}
+	})
+}
+
+func TestChanCancel(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		generator := eval(t, "__import__('itertools').count()", nil)
+		defer generator.Release()
+
+		context_, cancel := context.WithCancel(context.Background())
+		items, errs := generator.Chan(context_)
+
+		threadState := SaveThreadState()
+		first := <-items
+		cancel()
+		// Drains the item that might have been sent before the goroutine noticed the cancellation
+		var rest []*Reference
+		for item := range items {
+			rest = append(rest, item)
+		}
+		err := <-errs
+		threadState.Restore()
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		assertInt64s(t, toInt64s(t, []*Reference{first}), 0)
+		toInt64s(t, rest)
+	})
+}
+
+func TestChanInterpreter(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		interpreter := newTestInterpreter(t)
+		defer interpreter.Close()
+
+		if err := interpreter.Run("import sys\nsys.py4go_test = 'sub'\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := interpreter.Do(func() error {
+			// The generator would fail in the main interpreter
+			generator := eval(t, "(len(__import__('sys').py4go_test) + x for x in range(3))", nil)
+			defer generator.Release()
+
+			items, err := receiveChan(generator.Chan(context.Background()))
+			if err != nil {
+				t.Error(err)
+			}
+			assertInt64s(t, toInt64s(t, items), 3, 4, 5)
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestChanInterpreterClosed(t *testing.T) {
+	withGIL(t, func() {
+		interpreter := newTestInterpreter(t)
+		defer interpreter.Close()
+
+		var items <-chan *Reference
+		var errs <-chan error
+		if err := interpreter.Do(func() error {
+			generator := eval(t, "iter(range(3))", nil)
+			defer generator.Release()
+
+			items, errs = generator.Chan(context.Background())
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		threadState := SaveThreadState()
+		first := <-items
+		threadState.Restore()
+
+		// The goroutine might be waiting for the GIL in Do
+		if err := interpreter.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		threadState = SaveThreadState()
+		var received []*Reference
+		for item := range items {
+			received = append(received, item)
+		}
+		err := <-errs
+		threadState.Restore()
+
+		if err != ErrInterpreterClosed {
+			t.Errorf("expected ErrInterpreterClosed, got %v", err)
+		}
+
+		// At most the item that was waiting to be sent. The items are now invalid.
+		if len(received) > 1 {
+			t.Errorf("expected at most 1 item, got %d", len(received))
+		}
+		first.untrack()
+		for _, item := range received {
+			item.untrack()
+		}
+	})
+}
+
+func TestNewChanIterator(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		channel := make(chan int, 3)
+		channel <- 1
+		channel <- 2
+		channel <- 3
+		close(channel)
+
+		if iterator, err := NewChanIterator(channel); err == nil {
+			defer iterator.Release()
+			assertPython(t, iterator, "list(value) == [1, 2, 3]")
+		} else {
+			t.Fatal(err)
+		}
+
+		if _, err := NewChanIterator(1); err == nil {
+			t.Error("expected an error")
+		}
+		if _, err := NewChanIterator(make(chan<- int)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package python
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+
+static Py_ssize_t py4go_getRefCount(PyObject *object) {
+	return Py_REFCNT(object);
+}
+*/
+import "C"
+
+// Maximum depth of recorded Go stacks
+const MAX_LEAK_STACK = 32
+
+var leakTrackingEnabled int32
+
+var leakTracking struct {
+	references map[*Reference]*trackedReference
+	nextID     uint64
+	lock       sync.Mutex
+}
+
+type trackedReference struct {
+	id    uint64
+	stack []uintptr
+}
+
+func init() {
+	if debug {
+		EnableLeakTracking()
+	}
+}
+
+// When enabled, every owned reference is recorded together with the Go stack at its creation
+// until it is released. This is meant for tests and debugging, as it is costly. It is enabled by
+// default in debug builds (the "py4go_debug" build tag).
+//
+// Note that tracked references are kept alive, so they will not be finalized (see
+// EnableFinalizers).
+func EnableLeakTracking() {
+	leakTracking.lock.Lock()
+	defer leakTracking.lock.Unlock()
+
+	if leakTracking.references == nil {
+		leakTracking.references = make(map[*Reference]*trackedReference)
+	}
+	atomic.StoreInt32(&leakTrackingEnabled, 1)
+}
+
+// Stops recording and forgets all recorded references
+func DisableLeakTracking() {
+	leakTracking.lock.Lock()
+	defer leakTracking.lock.Unlock()
+
+	atomic.StoreInt32(&leakTrackingEnabled, 0)
+	leakTracking.references = nil
+}
+
+func LeakTrackingEnabled() bool {
+	return atomic.LoadInt32(&leakTrackingEnabled) == 1
+}
+
+//
+// LiveReference
+//
+
+type LiveReference struct {
+	Reference *Reference
+	ID        uint64 // increases in order of creation
+	TypeName  string
+
+	// The object's reference count, as reported by sys.getrefcount (without its own temporary
+	// reference)
+	RefCount int
+
+	// Go stack at creation, most recent call first
+	Stack []runtime.Frame
+}
+
+// fmt.Stringer interface
+func (self LiveReference) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s (refcount %d)", self.TypeName, self.RefCount)
+	for _, frame := range self.Stack {
+		fmt.Fprintf(&builder, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+	return builder.String()
+}
+
+// Returns the references that are currently owned and not yet released, in order of creation.
+// Requires leak tracking to be enabled (see EnableLeakTracking). Objects that this package caches
+// for the lifetime of an interpreter are not included.
+//
+// Acquires the GIL (see WithGIL).
+func LiveReferences() []LiveReference {
+	return liveReferencesSince(0)
+}
+
+// The subset of testing.TB used by CheckLeaks, so that this package does not depend on the testing
+// package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Cleanup(f func())
+}
+
+// Fails the test if owned references created during it (until its cleanup) were not released.
+// Enables leak tracking for the duration of the test if it is not already enabled.
+//
+// Call at the beginning of the test:
+//
+//	func TestMyBinding(t *testing.T) {
+//		python.CheckLeaks(t)
+//		...
+//	}
+func CheckLeaks(t TestingT) {
+	t.Helper()
+
+	enabled := LeakTrackingEnabled()
+	if !enabled {
+		EnableLeakTracking()
+	}
+
+	leakTracking.lock.Lock()
+	since := leakTracking.nextID
+	leakTracking.lock.Unlock()
+
+	t.Cleanup(func() {
+		leaks := liveReferencesSince(since)
+
+		if !enabled {
+			DisableLeakTracking()
+		}
+
+		for _, leak := range leaks {
+			t.Errorf("leaked Python reference: %s", leak)
+		}
+	})
+}
+
+func (self *Reference) track() {
+	if !LeakTrackingEnabled() {
+		return
+	}
+
+	stack := make([]uintptr, MAX_LEAK_STACK)
+	// Skip runtime.Callers, track, and NewReference (or Acquire)
+	stack = stack[:runtime.Callers(3, stack)]
+
+	leakTracking.lock.Lock()
+	defer leakTracking.lock.Unlock()
+
+	if leakTracking.references != nil {
+		leakTracking.nextID++
+		leakTracking.references[self] = &trackedReference{id: leakTracking.nextID, stack: stack}
+	}
+}
+
+func (self *Reference) untrack() {
+	if !LeakTrackingEnabled() {
+		return
+	}
+
+	leakTracking.lock.Lock()
+	defer leakTracking.lock.Unlock()
+
+	delete(leakTracking.references, self)
+}
+
+func liveReferencesSince(since uint64) []LiveReference {
+	leakTracking.lock.Lock()
+	var liveReferences []LiveReference
+	for reference, tracked := range leakTracking.references {
+		if tracked.id > since {
+			liveReferences = append(liveReferences, LiveReference{
+				Reference: reference,
+				ID:        tracked.id,
+				Stack:     getStackFrames(tracked.stack),
+			})
+		}
+	}
+	leakTracking.lock.Unlock()
+
+	sort.Slice(liveReferences, func(i int, j int) bool {
+		return liveReferences[i].ID < liveReferences[j].ID
+	})
+
+	WithGIL(func() error {
+		for index := range liveReferences {
+			liveReference := &liveReferences[index]
+			liveReference.TypeName = liveReference.Reference.Type().Name()
+			liveReference.RefCount = int(C.py4go_getRefCount(liveReference.Reference.Object))
+		}
+		return nil
+	})
+
+	return liveReferences
+}
+
+func getStackFrames(stack []uintptr) []runtime.Frame {
+	var frames []runtime.Frame
+	frames_ := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames_.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	return frames
+}
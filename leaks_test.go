@@ -0,0 +1,74 @@
+package python
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testLeaksT struct {
+	errors   []string
+	cleanups []func()
+}
+
+// TestingT interface
+func (self *testLeaksT) Helper() {}
+
+// TestingT interface
+func (self *testLeaksT) Errorf(format string, args ...interface{}) {
+	self.errors = append(self.errors, fmt.Sprintf(format, args...))
+}
+
+// TestingT interface
+func (self *testLeaksT) Cleanup(f func()) {
+	self.cleanups = append(self.cleanups, f)
+}
+
+func (self *testLeaksT) cleanup() {
+	for index := len(self.cleanups) - 1; index >= 0; index-- {
+		self.cleanups[index]()
+	}
+}
+
+func TestCheckLeaks(t *testing.T) {
+	t_ := new(testLeaksT)
+	CheckLeaks(t_)
+
+	var leaked *Reference
+	withGIL(t, func() {
+		eval(t, "'released'", nil).Release()
+		leaked = eval(t, "object()", nil)
+	})
+
+	var live bool
+	for _, reference := range LiveReferences() {
+		if reference.Reference == leaked {
+			live = true
+			if reference.TypeName != "object" {
+				t.Errorf("unexpected type name %s", reference.TypeName)
+			}
+
+			var found bool
+			for _, frame := range reference.Stack {
+				if strings.HasSuffix(frame.Function, ".TestCheckLeaks.func1") {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected the stack to include the test, got %v", reference.Stack)
+			}
+		}
+	}
+	if !live {
+		t.Error("expected LiveReferences to include the leaked reference")
+	}
+
+	t_.cleanup()
+	if (len(t_.errors) != 1) || !strings.HasPrefix(t_.errors[0], "leaked Python reference: object (refcount 1)") {
+		t.Errorf("expected one leak, got %q", t_.errors)
+	}
+
+	withGIL(t, func() {
+		leaked.Release()
+	})
+}
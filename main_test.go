@@ -0,0 +1,85 @@
+package python
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// The tests share one interpreter. The main thread releases the GIL, so each test acquires it via
+// withGIL.
+func TestMain(m *testing.M) {
+	if err := Initialize(); err != nil {
+		panic(err)
+	}
+	threadState := SaveThreadState()
+
+	code := m.Run()
+
+	threadState.Restore()
+	if err := Finalize(); (err != nil) && (code == 0) {
+		code = 1
+	}
+
+	os.Exit(code)
+}
+
+// Runs the test body while holding the GIL. Tests should also call CheckLeaks.
+func withGIL(t *testing.T, f func()) {
+	t.Helper()
+
+	WithGIL(func() error {
+		f()
+		return nil
+	})
+}
+
+// Releases the *Exception in the error chain, if there is one. Requires the GIL.
+func releaseError(err error) {
+	var exception *Exception
+	if errors.As(err, &exception) {
+		exception.Release()
+	}
+}
+
+// Evaluates the expression with the values as its globals
+func eval(t *testing.T, expression string, values map[string]interface{}) *Reference {
+	t.Helper()
+
+	globals := newGlobals(t, values)
+	defer globals.Release()
+
+	if result, err := Eval(expression, globals, nil); err == nil {
+		return result
+	} else {
+		t.Fatalf("%s: %s", expression, err)
+		return nil
+	}
+}
+
+// Fails the test unless the expression is true for "value"
+func assertPython(t *testing.T, value *Reference, expression string) {
+	t.Helper()
+
+	result := eval(t, expression, map[string]interface{}{"value": value})
+	defer result.Release()
+
+	if !result.ToBool() {
+		t.Errorf("expected %s for value %s", expression, value.String())
+	}
+}
+
+func newGlobals(t *testing.T, values map[string]interface{}) *Reference {
+	t.Helper()
+
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	if globals, err := NewReferenceFromValue(values); err == nil {
+		return globals
+	} else {
+		t.Fatal(err)
+		return nil
+	}
+}
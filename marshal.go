@@ -0,0 +1,336 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/concrete.html
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+const TAG = "py"
+
+var referenceType = reflect.TypeOf((*Reference)(nil))
+
+// Converts any Go value to a new Python reference by walking it with reflection:
+//
+// Nil pointers, interfaces, maps, and slices become None. Maps and structs become dicts, slices
+// become lists, arrays become tuples, and []byte becomes bytes. Struct fields can be renamed or
+// skipped via "py" tags, e.g. `py:"name,omitempty"` or `py:"-"`. Values of types registered with
+// NewGoClass become instances of their class. Cyclic values result in an error.
+func NewReferenceFromValue(value interface{}) (*Reference, error) {
+	return newReferenceFromValue(reflect.ValueOf(value))
+}
+
+func newReferenceFromValue(value reflect.Value) (*Reference, error) {
+	return new(marshaler).newReference(value)
+}
+
+func newTupleFromValue(value reflect.Value) (*Reference, error) {
+	return new(marshaler).newTuple(value)
+}
+
+// Returns a new owned reference, e.g. for singletons, which are shared
+func newAcquiredReference(object *C.PyObject) *Reference {
+	C.Py_IncRef(object)
+	return NewReference(object)
+}
+
+//
+// marshaler
+//
+
+// Pointers, maps, and slices are identified by address and type (and length, for slices)
+type marshalVisit struct {
+	pointer uintptr
+	type_   reflect.Type
+	length  int
+}
+
+// Keeps track of the pointers, maps, and slices that are being converted, so that we can return
+// an error for cyclic values instead of recursing forever. Values that are merely shared are
+// converted again wherever they appear.
+type marshaler struct {
+	visiting map[marshalVisit]struct{}
+}
+
+// Call leave with the returned visit when done converting the value
+func (self *marshaler) enter(value reflect.Value) (marshalVisit, error) {
+	visit := marshalVisit{pointer: value.Pointer(), type_: value.Type()}
+	if value.Kind() == reflect.Slice {
+		visit.length = value.Len()
+	}
+
+	if _, ok := self.visiting[visit]; ok {
+		return visit, fmt.Errorf("cyclic value via %s", value.Type())
+	}
+
+	if self.visiting == nil {
+		self.visiting = make(map[marshalVisit]struct{})
+	}
+	self.visiting[visit] = struct{}{}
+	return visit, nil
+}
+
+func (self *marshaler) leave(visit marshalVisit) {
+	delete(self.visiting, visit)
+}
+
+func (self *marshaler) newReference(value reflect.Value) (*Reference, error) {
+	if value.IsValid() {
+		if class := getGoClass(value.Type()); class != nil {
+			if (value.Kind() != reflect.Ptr) || !value.IsNil() {
+				return class.NewObject(value.Interface())
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Invalid:
+		return newAcquiredReference(C.Py_None), nil
+
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return newAcquiredReference(C.Py_None), nil
+		} else if value.Type() == referenceType {
+			return newAcquiredReference(value.Interface().(*Reference).Object), nil
+		} else if value.Kind() == reflect.Ptr {
+			if visit, err := self.enter(value); err == nil {
+				defer self.leave(visit)
+				return self.newReference(value.Elem())
+			} else {
+				return nil, err
+			}
+		} else {
+			return self.newReference(value.Elem())
+		}
+
+	case reflect.Bool:
+		if value.Bool() {
+			return newAcquiredReference(C.Py_True), nil
+		} else {
+			return newAcquiredReference(C.Py_False), nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewLong(value.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewUnsignedLong(value.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		return NewFloat(value.Float())
+
+	case reflect.String:
+		return NewUnicode(value.String())
+
+	case reflect.Slice:
+		if value.IsNil() {
+			return newAcquiredReference(C.Py_None), nil
+		} else if value.Type().Elem().Kind() == reflect.Uint8 {
+			return NewBytes(value.Bytes())
+		} else if visit, err := self.enter(value); err == nil {
+			defer self.leave(visit)
+			return self.newList(value)
+		} else {
+			return nil, err
+		}
+
+	case reflect.Array:
+		return self.newTuple(value)
+
+	case reflect.Map:
+		if value.IsNil() {
+			return newAcquiredReference(C.Py_None), nil
+		} else if visit, err := self.enter(value); err == nil {
+			defer self.leave(visit)
+			return self.newDictFromMap(value)
+		} else {
+			return nil, err
+		}
+
+	case reflect.Struct:
+		return self.newDictFromStruct(value)
+	}
+
+	return nil, fmt.Errorf("unsupported type: %s", value.Type())
+}
+
+func (self *marshaler) newList(value reflect.Value) (*Reference, error) {
+	length := value.Len()
+	if list, err := NewListRaw(length); err == nil {
+		for index := 0; index < length; index++ {
+			if item, err := self.newReference(value.Index(index)); err == nil {
+				// Steals the item reference
+				if err := list.SetListItem(index, item); err != nil {
+					list.Release()
+					return nil, err
+				}
+			} else {
+				list.Release()
+				return nil, err
+			}
+		}
+		return list, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *marshaler) newTuple(value reflect.Value) (*Reference, error) {
+	length := value.Len()
+	if tuple, err := NewTupleRaw(length); err == nil {
+		for index := 0; index < length; index++ {
+			if item, err := self.newReference(value.Index(index)); err == nil {
+				// Steals the item reference
+				if err := tuple.SetTupleItem(index, item); err != nil {
+					tuple.Release()
+					return nil, err
+				}
+			} else {
+				tuple.Release()
+				return nil, err
+			}
+		}
+		return tuple, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *marshaler) newDictFromMap(value reflect.Value) (*Reference, error) {
+	if dict, err := NewDict(); err == nil {
+		iterator := value.MapRange()
+		for iterator.Next() {
+			if err := self.setDictItem(dict, iterator.Key(), iterator.Value()); err != nil {
+				dict.Release()
+				return nil, err
+			}
+		}
+		return dict, nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *marshaler) newDictFromStruct(value reflect.Value) (*Reference, error) {
+	if dict, err := NewDict(); err == nil {
+		if err := self.setDictItemsFromStruct(dict, value); err == nil {
+			return dict, nil
+		} else {
+			dict.Release()
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+func (self *marshaler) setDictItemsFromStruct(dict *Reference, value reflect.Value) error {
+	type_ := value.Type()
+	for index := 0; index < type_.NumField(); index++ {
+		field := type_.Field(index)
+		fieldValue := value.Field(index)
+
+		if field.Anonymous && (field.Tag.Get(TAG) == "") {
+			// Flatten embedded structs
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				if visit, err := self.enter(embedded); err == nil {
+					defer self.leave(visit)
+				} else {
+					return err
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := self.setDictItemsFromStruct(dict, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			// Unexported
+			continue
+		}
+
+		name, omitEmpty, skip := parseFieldTag(field)
+		if skip || (omitEmpty && isEmptyValue(fieldValue)) {
+			continue
+		}
+
+		if err := self.setDictItem(dict, reflect.ValueOf(name), fieldValue); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func (self *marshaler) setDictItem(dict *Reference, key reflect.Value, value reflect.Value) error {
+	if key_, err := self.newReference(key); err == nil {
+		defer key_.Release()
+
+		if value_, err := self.newReference(value); err == nil {
+			defer value_.Release()
+
+			return dict.SetDictItem(key_, value_)
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Returns the field's name from its "py" tag, falling back to the Go name
+func parseFieldTag(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get(TAG)
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name := field.Name
+	omitEmpty := false
+
+	split := strings.Split(tag, ",")
+	if split[0] != "" {
+		name = split[0]
+	}
+	for _, option := range split[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, false
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
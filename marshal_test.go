@@ -0,0 +1,199 @@
+package python
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testPerson struct {
+	Name    string            `py:"name"`
+	Age     int               `py:"age"`
+	Email   string            `py:"email,omitempty"`
+	Secret  string            `py:"-"`
+	Tags    []string          `py:"tags"`
+	Scores  map[string]uint64 `py:"scores"`
+	Partner *testPerson       `py:"partner"`
+	Point   [2]float64        `py:"point"`
+	Data    []byte            `py:"data"`
+
+	private int
+}
+
+func TestNewReferenceFromValueScalars(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, test := range []struct {
+			value      interface{}
+			expression string
+		}{
+			{nil, "value is None"},
+			{true, "value is True"},
+			{int16(-7), "value == -7"},
+			{uint64(math.MaxUint64), "value == 2**64 - 1"},
+			{1.5, "value == 1.5"},
+			{"ünï", "value == 'ünï'"},
+			{[]byte("abc"), "value == b'abc'"},
+			{(*testPerson)(nil), "value is None"},
+			{[]string(nil), "value is None"},
+		} {
+			if value, err := NewReferenceFromValue(test.value); err == nil {
+				assertPython(t, value, test.expression)
+				value.Release()
+			} else {
+				t.Errorf("%#v: %s", test.value, err)
+			}
+		}
+	})
+}
+
+func TestNewReferenceFromValueStruct(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		person := testPerson{
+			Name:    "Alice",
+			Age:     30,
+			Secret:  "hidden",
+			Tags:    []string{"a", "b"},
+			Scores:  map[string]uint64{"go": 10},
+			Partner: &testPerson{Name: "Bob"},
+			Point:   [2]float64{1, 2},
+			Data:    []byte{0, 1},
+			private: 1,
+		}
+
+		if value, err := NewReferenceFromValue(person); err == nil {
+			defer value.Release()
+
+			assertPython(t, value, "set(value) == {'name', 'age', 'tags', 'scores', 'partner', 'point', 'data'}")
+			assertPython(t, value, "value['name'] == 'Alice' and value['age'] == 30")
+			assertPython(t, value, "value['tags'] == ['a', 'b'] and value['scores'] == {'go': 10}")
+			assertPython(t, value, "value['partner']['name'] == 'Bob' and value['partner']['partner'] is None")
+			assertPython(t, value, "value['point'] == (1.0, 2.0) and value['data'] == b'\\x00\\x01'")
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestNewReferenceFromValueUnsupported(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		if value, err := NewReferenceFromValue(make(chan int)); err == nil {
+			value.Release()
+			t.Error("expected an error for a channel")
+		}
+	})
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		person := testPerson{
+			Name:    "Alice",
+			Age:     30,
+			Email:   "alice@example.com",
+			Tags:    []string{"a", "b"},
+			Scores:  map[string]uint64{"go": math.MaxUint64},
+			Partner: &testPerson{Name: "Bob", Tags: []string{}},
+			Point:   [2]float64{1.5, -2},
+			Data:    []byte("data"),
+		}
+
+		if value, err := NewReferenceFromValue(person); err == nil {
+			defer value.Release()
+
+			var person_ testPerson
+			if err := value.Unmarshal(&person_); err == nil {
+				if !reflect.DeepEqual(person, person_) {
+					t.Errorf("expected %#v, got %#v", person, person_)
+				}
+			} else {
+				t.Fatal(err)
+			}
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestMarshalRoundTripGeneric(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		generic := map[string]interface{}{
+			"int":    int64(1),
+			"float":  2.5,
+			"string": "three",
+			"bool":   true,
+			"list":   []interface{}{int64(1), "two"},
+			"nested": map[string]interface{}{"none": nil},
+		}
+
+		if value, err := NewReferenceFromValue(generic); err == nil {
+			defer value.Release()
+
+			var generic_ interface{}
+			if err := value.Unmarshal(&generic_); err == nil {
+				if !reflect.DeepEqual(generic, generic_) {
+					t.Errorf("expected %#v, got %#v", generic, generic_)
+				}
+			} else {
+				t.Fatal(err)
+			}
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+type testNode struct {
+	Name string
+	Next *testNode
+}
+
+type testEmbeddedNode struct {
+	*testEmbeddedNode
+	Name string
+}
+
+func TestNewReferenceFromValueCycles(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		node := &testNode{Name: "a"}
+		node.Next = &testNode{Name: "b", Next: node}
+
+		embedded := &testEmbeddedNode{Name: "a"}
+		embedded.testEmbeddedNode = embedded
+
+		map_ := map[string]interface{}{}
+		map_["self"] = map_
+
+		slice := []interface{}{nil}
+		slice[0] = slice
+
+		for _, value := range []interface{}{node, embedded, map_, slice} {
+			if value_, err := NewReferenceFromValue(value); err == nil {
+				value_.Release()
+				t.Errorf("%T: expected an error", value)
+			} else if !strings.Contains(err.Error(), "cyclic value") {
+				t.Errorf("%T: unexpected error %s", value, err)
+			}
+		}
+
+		// Shared values that are not cyclic are converted where they appear
+		shared := &testNode{Name: "shared"}
+		if value, err := NewReferenceFromValue([]*testNode{shared, shared}); err == nil {
+			assertPython(t, value, "value[0] == value[1] == {'Name': 'shared', 'Next': None}")
+			value.Release()
+		} else {
+			t.Error(err)
+		}
+	})
+}
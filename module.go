@@ -39,8 +39,7 @@ func NewModuleRaw(name string) (*Reference, error) {
 
 func (self *Reference) GetModuleName() (string, error) {
 	if name := C.PyModule_GetName(self.Object); name != nil {
-		defer C.free(unsafe.Pointer(name)) // TODO: need this?
-
+		// Note: the name belongs to the module and must not be freed
 		return C.GoString(name), nil
 	} else {
 		return "", GetError()
@@ -53,10 +52,8 @@ func (self *Reference) EnableModule() error {
 		defer name_.Release()
 
 		if moduleDict := C.PyImport_GetModuleDict(); moduleDict != nil {
-			moduleDict_ := NewReference(moduleDict)
-			defer moduleDict_.Release()
-
-			return moduleDict_.SetDictItem(name_, self)
+			// The module dict is borrowed
+			return NewBorrowedReference(moduleDict).SetDictItem(name_, self)
 		} else {
 			return GetError()
 		}
@@ -0,0 +1,6 @@
+//go:build !py4go_debug
+// +build !py4go_debug
+
+package python
+
+const debug = false
@@ -31,12 +31,37 @@ func (self *Reference) String() string {
 	}
 }
 
+// Holds an additional reference, which must be released separately. Acquiring a borrowed reference
+// makes it owned.
 func (self *Reference) Acquire() {
+	if self.ownership == Released {
+		panicIfDebug("acquiring released reference")
+	}
+
 	C.Py_IncRef(self.Object)
+	self.count++
+	if self.count == 1 {
+		self.ownership = Owned
+		self.setFinalizer()
+		self.track()
+	}
 }
 
+// Releases one of the references we hold. A no-op for borrowed and stolen references.
+//
+// In debug builds (the "py4go_debug" build tag) releasing more times than we hold panics.
 func (self *Reference) Release() {
-	C.Py_DecRef(self.Object)
+	if self.count > 0 {
+		C.Py_DecRef(self.Object)
+		self.count--
+		if self.count == 0 {
+			self.ownership = Released
+			self.clearFinalizer()
+			self.untrack()
+		}
+	} else if self.ownership == Released {
+		panicIfDebug("double release of reference")
+	}
 }
 
 func (self *Reference) Str() (*Reference, error) {
@@ -47,7 +72,19 @@ func (self *Reference) Str() (*Reference, error) {
 	}
 }
 
+func (self *Reference) Repr() (*Reference, error) {
+	if repr := C.PyObject_Repr(self.Object); repr != nil {
+		return NewReference(repr), nil
+	} else {
+		return nil, GetError()
+	}
+}
+
 func (self *Reference) GetAttr(name string) (*Reference, error) {
+	if err := checkInterpreters(self); err != nil {
+		return nil, err
+	}
+
 	name_ := C.CString(name)
 	defer C.free(unsafe.Pointer(name_))
 
@@ -59,6 +96,10 @@ func (self *Reference) GetAttr(name string) (*Reference, error) {
 }
 
 func (self *Reference) SetAttr(name string, reference *Reference) error {
+	if err := checkInterpreters(self, reference); err != nil {
+		return err
+	}
+
 	name_ := C.CString(name)
 	defer C.free(unsafe.Pointer(name_))
 
@@ -70,8 +111,22 @@ func (self *Reference) SetAttr(name string, reference *Reference) error {
 }
 
 func (self *Reference) Call(args ...interface{}) (*Reference, error) {
+	if err := checkInterpreters(self); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if arg_, ok := arg.(*Reference); ok {
+			if err := checkInterpreters(arg_); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if args_, err := NewTuple(args...); err == nil {
+		defer args_.Release()
+
 		if kw, err := NewDict(); err == nil {
+			defer kw.Release()
 			return self.CallRaw(args_, kw)
 		} else {
 			return nil, err
@@ -82,6 +137,10 @@ func (self *Reference) Call(args ...interface{}) (*Reference, error) {
 }
 
 func (self *Reference) CallRaw(args *Reference, kw *Reference) (*Reference, error) {
+	if err := checkInterpreters(self, args, kw); err != nil {
+		return nil, err
+	}
+
 	if r := C.PyObject_Call(self.Object, args.Object, kw.Object); r != nil {
 		return NewReference(r), nil
 	} else {
@@ -1,27 +1,283 @@
 package python
 
+// See:
+//   https://docs.python.org/3/library/sys.html#sys.path
+//   https://docs.python.org/3/library/importlib.html#importlib.invalidate_caches
+
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"unsafe"
 )
 
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
 const PYTHONPATH = "PYTHONPATH"
 
+//
+// Before initialization
+//
+
+// Sets the PYTHONPATH environment variable, which is used by Initialize
 func SetPythonPath(path ...string) {
-	path_ := filepath.Join(path...)
-	os.Setenv(PYTHONPATH, path_)
+	os.Setenv(PYTHONPATH, joinPathList(path))
 }
 
 func AppendPythonPath(path ...string) {
 	path_ := filepath.SplitList(os.Getenv(PYTHONPATH))
 	path_ = append(path_, path...)
-	path__ := filepath.Join(path_...)
-	os.Setenv(PYTHONPATH, path__)
+	os.Setenv(PYTHONPATH, joinPathList(path_))
 }
 
 func PrependPythonPath(path ...string) {
 	path_ := filepath.SplitList(os.Getenv(PYTHONPATH))
 	path_ = append(path, path_...)
-	path__ := filepath.Join(path_...)
-	os.Setenv(PYTHONPATH, path__)
+	os.Setenv(PYTHONPATH, joinPathList(path_))
+}
+
+func joinPathList(path []string) string {
+	return strings.Join(path, string(os.PathListSeparator))
+}
+
+//
+// After initialization
+//
+
+// Returns a copy of sys.path of the current interpreter. Entries that are not strings are skipped
+// (the import system ignores them, too).
+func SysPath() ([]string, error) {
+	if sysPath, err := getSysPath(); err == nil {
+		items, _ := getSequenceItems(sysPath)
+		path := make([]string, 0, len(items))
+		for _, item := range items {
+			if item.IsUnicode() {
+				if path_, err := item.ToString(); err == nil {
+					path = append(path, path_)
+				} else {
+					return nil, err
+				}
+			}
+		}
+		return path, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Replaces sys.path and invalidates the import caches
+func SetSysPath(path ...string) error {
+	if path == nil {
+		// Otherwise it would be converted to None
+		path = []string{}
+	}
+
+	// The new sys.path might not allow importing importlib
+	importlib, err := Import("importlib")
+	if err != nil {
+		return err
+	}
+	defer importlib.Release()
+
+	if sysPath, err := getSysPath(); err == nil {
+		if path_, err := NewReferenceFromValue(path); err == nil {
+			defer path_.Release()
+
+			// sys.path[:] = path
+			if C.PyList_SetSlice(sysPath.Object, 0, C.PY_SSIZE_T_MAX, path_.Object) == 0 {
+				return invalidateImportCaches(importlib)
+			} else {
+				return GetError()
+			}
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Inserts into sys.path before the index (like list.insert) and invalidates the import caches
+func InsertSysPath(index int, path string) error {
+	if sysPath, err := getSysPath(); err == nil {
+		if path_, err := NewUnicode(path); err == nil {
+			defer path_.Release()
+
+			if C.PyList_Insert(sysPath.Object, C.Py_ssize_t(index), path_.Object) == 0 {
+				return InvalidateImportCaches()
+			} else {
+				return GetError()
+			}
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Inserts at the beginning of sys.path (in order) and invalidates the import caches
+func PrependSysPath(path ...string) error {
+	for index := len(path) - 1; index >= 0; index-- {
+		if err := InsertSysPath(0, path[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Appends to sys.path and invalidates the import caches
+func AppendSysPath(path ...string) error {
+	if sysPath, err := getSysPath(); err == nil {
+		for _, path_ := range path {
+			if path__, err := NewUnicode(path_); err == nil {
+				result := C.PyList_Append(sysPath.Object, path__.Object)
+				path__.Release()
+				if result != 0 {
+					return GetError()
+				}
+			} else {
+				return err
+			}
+		}
+		return InvalidateImportCaches()
+	} else {
+		return err
+	}
+}
+
+// Removes all occurrences from sys.path and invalidates the import caches. Returns true if any
+// were removed.
+func RemoveSysPath(path string) (bool, error) {
+	return filterSysPath(func(path_ string, seen map[string]bool) bool {
+		return path_ != path
+	})
+}
+
+// Removes all but the first occurrence of each entry from sys.path and invalidates the import
+// caches. Returns true if any were removed.
+func DedupeSysPath() (bool, error) {
+	return filterSysPath(func(path string, seen map[string]bool) bool {
+		return !seen[path]
+	})
+}
+
+func SysPathContains(path string) (bool, error) {
+	if path_, err := SysPath(); err == nil {
+		for _, path__ := range path_ {
+			if path__ == path {
+				return true, nil
+			}
+		}
+		return false, nil
+	} else {
+		return false, err
+	}
+}
+
+// Calls importlib.invalidate_caches, which is necessary for the import system to notice changes
+// to sys.path and to the directories in it
+func InvalidateImportCaches() error {
+	if importlib, err := Import("importlib"); err == nil {
+		defer importlib.Release()
+
+		return invalidateImportCaches(importlib)
+	} else {
+		return err
+	}
+}
+
+func invalidateImportCaches(importlib *Reference) error {
+	if invalidateCaches, err := importlib.GetAttr("invalidate_caches"); err == nil {
+		defer invalidateCaches.Release()
+
+		if r, err := invalidateCaches.Call(); err == nil {
+			r.Release()
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Returns a borrowed reference
+func getSysPath() (*Reference, error) {
+	return getSysList("path")
+}
+
+// Keeps only the entries for which keep returns true. Entries that are not strings are always
+// kept.
+func filterSysPath(keep func(path string, seen map[string]bool) bool) (bool, error) {
+	// The filtered sys.path might not allow importing importlib
+	importlib, err := Import("importlib")
+	if err != nil {
+		return false, err
+	}
+	defer importlib.Release()
+
+	if sysPath, err := getSysPath(); err == nil {
+		// Borrowed references
+		items, _ := getSequenceItems(sysPath)
+
+		var filtered []*Reference
+		seen := make(map[string]bool)
+		for _, item := range items {
+			if item.IsUnicode() {
+				if path, err := item.ToString(); err == nil {
+					keep_ := keep(path, seen)
+					seen[path] = true
+					if !keep_ {
+						continue
+					}
+				} else {
+					return false, err
+				}
+			}
+			filtered = append(filtered, item)
+		}
+
+		if len(filtered) == len(items) {
+			return false, nil
+		}
+
+		if filtered == nil {
+			// Otherwise it would be converted to None
+			filtered = []*Reference{}
+		}
+
+		if filtered_, err := NewReferenceFromValue(filtered); err == nil {
+			defer filtered_.Release()
+
+			// sys.path[:] = filtered
+			if C.PyList_SetSlice(sysPath.Object, 0, C.PY_SSIZE_T_MAX, filtered_.Object) == 0 {
+				return true, invalidateImportCaches(importlib)
+			} else {
+				return false, GetError()
+			}
+		} else {
+			return false, err
+		}
+	} else {
+		return false, err
+	}
+}
+
+// Returns a borrowed reference
+func getSysList(name string) (*Reference, error) {
+	name_ := C.CString(name)
+	defer C.free(unsafe.Pointer(name_))
+
+	if list := C.PySys_GetObject(name_); list != nil {
+		if list_ := NewBorrowedReference(list); list_.IsList() {
+			return list_, nil
+		}
+	}
+	return nil, errors.New("sys." + name + " is not a list")
 }
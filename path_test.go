@@ -0,0 +1,151 @@
+package python
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Restores sys.path when the test is done
+func scopeSysPath(t *testing.T) {
+	t.Helper()
+
+	var path []string
+	withGIL(t, func() {
+		var err error
+		if path, err = SysPath(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Cleanup(func() {
+		withGIL(t, func() {
+			if err := SetSysPath(path...); err != nil {
+				t.Error(err)
+			}
+		})
+	})
+}
+
+func assertSysPath(t *testing.T, expected ...string) {
+	t.Helper()
+
+	if expected == nil {
+		expected = []string{}
+	}
+
+	if path, err := SysPath(); err == nil {
+		if !reflect.DeepEqual(path, expected) {
+			t.Errorf("expected sys.path %q, got %q", expected, path)
+		}
+	} else {
+		t.Error(err)
+	}
+}
+
+func TestPythonPath(t *testing.T) {
+	t.Setenv(PYTHONPATH, "")
+
+	SetPythonPath("b")
+	AppendPythonPath("c", "d")
+	PrependPythonPath("a")
+
+	if path := os.Getenv(PYTHONPATH); path != joinPathList([]string{"a", "b", "c", "d"}) {
+		t.Errorf("unexpected %s", path)
+	}
+}
+
+func TestSysPath(t *testing.T) {
+	CheckLeaks(t)
+	scopeSysPath(t)
+
+	withGIL(t, func() {
+		if err := SetSysPath("b"); err != nil {
+			t.Fatal(err)
+		}
+		if err := InsertSysPath(1, "c"); err != nil {
+			t.Fatal(err)
+		}
+		if err := PrependSysPath("a1", "a2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := AppendSysPath("d", "b"); err != nil {
+			t.Fatal(err)
+		}
+		assertSysPath(t, "a1", "a2", "b", "c", "d", "b")
+
+		if contains, err := SysPathContains("c"); (err != nil) || !contains {
+			t.Errorf("expected sys.path to contain c: %v", err)
+		}
+		if contains, err := SysPathContains("e"); (err != nil) || contains {
+			t.Errorf("expected sys.path not to contain e: %v", err)
+		}
+
+		if removed, err := DedupeSysPath(); (err != nil) || !removed {
+			t.Errorf("expected a duplicate to be removed: %v", err)
+		}
+		assertSysPath(t, "a1", "a2", "b", "c", "d")
+
+		if removed, err := RemoveSysPath("c"); (err != nil) || !removed {
+			t.Errorf("expected c to be removed: %v", err)
+		}
+		if removed, err := RemoveSysPath("c"); (err != nil) || removed {
+			t.Errorf("expected nothing to be removed: %v", err)
+		}
+		assertSysPath(t, "a1", "a2", "b", "d")
+
+		if err := SetSysPath(); err != nil {
+			t.Fatal(err)
+		}
+		assertSysPath(t)
+	})
+}
+
+func TestSysPathNotStrings(t *testing.T) {
+	CheckLeaks(t)
+	scopeSysPath(t)
+
+	withGIL(t, func() {
+		if err := SetSysPath("a", "a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Exec("import sys\nsys.path.insert(1, None)\n", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Skipped by SysPath but kept when filtering
+		assertSysPath(t, "a", "a")
+		if _, err := DedupeSysPath(); err != nil {
+			t.Fatal(err)
+		}
+		assertPython(t, None, "__import__('sys').path == ['a', None]")
+	})
+}
+
+func TestSysPathImport(t *testing.T) {
+	CheckLeaks(t)
+	scopeSysPath(t)
+
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "py4go_path_test.py"), []byte("value = 42\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	withGIL(t, func() {
+		if err := AppendSysPath(directory); err != nil {
+			t.Fatal(err)
+		}
+
+		if module, err := Import("py4go_path_test"); err == nil {
+			assertPython(t, module, "value.value == 42")
+			module.Release()
+		} else {
+			t.Error(err)
+		}
+
+		if err := Exec("import sys\ndel sys.modules['py4go_path_test']\n", nil, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
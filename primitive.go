@@ -4,7 +4,6 @@ package python
 //   https://docs.python.org/3/c-api/concrete.html
 
 import (
-	"fmt"
 	"unsafe"
 )
 
@@ -15,6 +14,8 @@ import (
 */
 import "C"
 
+// Note that nil, bools, and *Reference values result in borrowed references. A nil *Reference
+// results in None.
 func NewPrimitiveReference(value interface{}) (*Reference, error) {
 	if value == nil {
 		return None, nil
@@ -22,7 +23,10 @@ func NewPrimitiveReference(value interface{}) (*Reference, error) {
 
 	switch value_ := value.(type) {
 	case *Reference:
-		return value_, nil
+		if value_ == nil {
+			return None, nil
+		}
+		return NewBorrowedReference(value_.Object), nil
 	case bool:
 		if value_ {
 			return True, nil
@@ -49,14 +53,16 @@ func NewPrimitiveReference(value interface{}) (*Reference, error) {
 		return NewBytes(value_)
 	}
 
-	return nil, fmt.Errorf("unsupported primitive: %s", value)
+	// Maps, structs, slices, etc.
+	return NewReferenceFromValue(value)
 }
 
 //
 // None
 //
 
-var None = NewReference(C.Py_None)
+// Borrowed
+var None = NewBorrowedReference(C.Py_None)
 
 //
 // Bool
@@ -64,8 +70,9 @@ var None = NewReference(C.Py_None)
 
 var BoolType = NewType(&C.PyBool_Type)
 
-var True = NewReference(C.Py_True)
-var False = NewReference(C.Py_False)
+// Borrowed
+var True = NewBorrowedReference(C.Py_True)
+var False = NewBorrowedReference(C.Py_False)
 
 func (self *Reference) IsBool() bool {
 	return self.Type().IsSubtype(BoolType)
@@ -95,6 +102,14 @@ func NewLong(value int64) (*Reference, error) {
 	}
 }
 
+func NewUnsignedLong(value uint64) (*Reference, error) {
+	if long := C.PyLong_FromUnsignedLongLong(C.ulonglong(value)); long != nil {
+		return NewReference(long), nil
+	} else {
+		return nil, GetError()
+	}
+}
+
 func (self *Reference) IsLong() bool {
 	// More efficient to use the flag
 	return self.Type().HasFlag(C.Py_TPFLAGS_LONG_SUBCLASS)
@@ -181,9 +196,11 @@ func NewTuple(items ...interface{}) (*Reference, error) {
 		for index, item := range items {
 			if item_, err := NewPrimitiveReference(item); err == nil {
 				if err := tuple.SetTupleItem(index, item_); err != nil {
+					tuple.Release()
 					return nil, err
 				}
 			} else {
+				tuple.Release()
 				return nil, err
 			}
 		}
@@ -206,8 +223,9 @@ func (self *Reference) IsTuple() bool {
 	return self.Type().HasFlag(C.Py_TPFLAGS_TUPLE_SUBCLASS)
 }
 
+// Steals the item reference (even on failure). If the item is borrowed it is acquired first.
 func (self *Reference) SetTupleItem(index int, item *Reference) error {
-	if C.PyTuple_SetItem(self.Object, C.int64_t(index), item.Object) == 0 {
+	if C.PyTuple_SetItem(self.Object, C.int64_t(index), item.steal()) == 0 {
 		return nil
 	} else {
 		return GetError()
@@ -225,9 +243,11 @@ func NewList(items ...interface{}) (*Reference, error) {
 		for index, item := range items {
 			if item_, err := NewPrimitiveReference(item); err == nil {
 				if err := list.SetListItem(index, item_); err != nil {
+					list.Release()
 					return nil, err
 				}
 			} else {
+				list.Release()
 				return nil, err
 			}
 		}
@@ -250,8 +270,9 @@ func (self *Reference) IsList() bool {
 	return self.Type().HasFlag(C.Py_TPFLAGS_LIST_SUBCLASS)
 }
 
+// Steals the item reference (even on failure). If the item is borrowed it is acquired first.
 func (self *Reference) SetListItem(index int, item *Reference) error {
-	if C.PyList_SetItem(self.Object, C.int64_t(index), item.Object) == 0 {
+	if C.PyList_SetItem(self.Object, C.int64_t(index), item.steal()) == 0 {
 		return nil
 	} else {
 		return GetError()
@@ -277,7 +298,12 @@ func (self *Reference) IsDict() bool {
 	return self.Type().HasFlag(C.Py_TPFLAGS_DICT_SUBCLASS)
 }
 
+// Does not steal the key and value references
 func (self *Reference) SetDictItem(key *Reference, value *Reference) error {
+	if err := checkInterpreters(self, key, value); err != nil {
+		return err
+	}
+
 	if C.PyDict_SetItem(self.Object, key.Object, value.Object) == 0 {
 		return nil
 	} else {
@@ -0,0 +1,37 @@
+package python
+
+import (
+	"testing"
+)
+
+func TestNewPrimitiveReferenceNil(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		var reference *Reference
+
+		if tuple, err := NewTuple(nil, reference); err == nil {
+			assertPython(t, tuple, "value == (None, None)")
+			tuple.Release()
+		} else {
+			t.Error(err)
+		}
+
+		if list, err := NewList(reference); err == nil {
+			assertPython(t, list, "value == [None]")
+			list.Release()
+		} else {
+			t.Error(err)
+		}
+
+		function := eval(t, "lambda value: value is None", nil)
+		defer function.Release()
+
+		if result, err := function.Call(reference); err == nil {
+			assertPython(t, result, "value is True")
+			result.Release()
+		} else {
+			t.Error(err)
+		}
+	})
+}
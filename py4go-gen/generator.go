@@ -0,0 +1,460 @@
+package main
+
+// See:
+//   https://docs.python.org/3/c-api/structures.html
+//   https://pkg.go.dev/go/types
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Already imported by the templates
+var templateImports = map[string]bool{
+	"fmt":                     true,
+	"github.com/tliron/py4go": true,
+}
+
+//
+// Generator
+//
+
+type Generator struct {
+	Dir          string
+	Module       string
+	Functions    []string
+	CreateModule string
+	Prefix       string
+
+	packageName  string
+	functions    []*Function
+	packageNames map[string]string // import path to name in the generated code
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		Dir:          ".",
+		CreateModule: "CreateModule",
+		Prefix:       "py4go_",
+		packageNames: map[string]string{
+			"fmt":                     "fmt",
+			"github.com/tliron/py4go": "python",
+		},
+	}
+}
+
+func (self *Generator) Generate() error {
+	if err := self.load(); err != nil {
+		return err
+	}
+
+	if len(self.functions) == 0 {
+		return fmt.Errorf("no functions to expose in %s", self.Dir)
+	}
+
+	if err := self.write(self.Prefix+"export.go", exportTemplate); err != nil {
+		return err
+	}
+
+	return self.write(self.Prefix+"module.go", moduleTemplate)
+}
+
+func (self *Generator) load() error {
+	buildPackage, err := build.ImportDir(self.Dir, 0)
+	if err != nil {
+		return err
+	}
+
+	self.packageName = buildPackage.Name
+	if self.Module == "" {
+		self.Module = self.packageName
+	}
+
+	fileSet := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range append(buildPackage.GoFiles, buildPackage.CgoFiles...) {
+		if strings.HasPrefix(name, self.Prefix) {
+			// Our own output
+			continue
+		}
+
+		if file, err := parser.ParseFile(fileSet, filepath.Join(self.Dir, name), nil, 0); err == nil {
+			files = append(files, file)
+		} else {
+			return err
+		}
+	}
+
+	config := types.Config{
+		Importer:    importer.ForCompiler(fileSet, "source", nil),
+		FakeImportC: true,
+		Error:       func(err error) {}, // we only need the signatures
+	}
+	package_, _ := config.Check(self.packageName, fileSet, files, nil)
+	scope := package_.Scope()
+
+	var names []string
+	if len(self.Functions) > 0 {
+		names = self.Functions
+	} else {
+		names = scope.Names()
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		object := scope.Lookup(name)
+		if object == nil {
+			return fmt.Errorf("function not found: %s", name)
+		}
+
+		function, ok := object.(*types.Func)
+		if !ok {
+			if len(self.Functions) > 0 {
+				return fmt.Errorf("not a function: %s", name)
+			}
+			continue
+		}
+
+		if !function.Exported() {
+			if len(self.Functions) > 0 {
+				return fmt.Errorf("function not exported: %s", name)
+			}
+			continue
+		}
+
+		if function_, err := self.newFunction(function, package_); err == nil {
+			self.functions = append(self.functions, function_)
+		} else if len(self.Functions) > 0 {
+			return err
+		} else {
+			fmt.Fprintf(os.Stderr, "py4go-gen: skipping %s\n", err)
+		}
+	}
+
+	return nil
+}
+
+func (self *Generator) newFunction(function *types.Func, package_ *types.Package) (*Function, error) {
+	signature := function.Type().(*types.Signature)
+	if signature.Variadic() {
+		return nil, fmt.Errorf("%s: variadic functions are not supported", function.Name())
+	}
+
+	pythonName := toSnakeCase(function.Name())
+	function_ := Function{
+		GoName:     function.Name(),
+		PythonName: pythonName,
+		GoExport:   fmt.Sprintf("go_%s_%s", self.Module, function.Name()),
+		PyWrapper:  fmt.Sprintf("py_%s_%s", self.Module, pythonName),
+	}
+
+	// Named types from other packages must be imported
+	qualifier := func(package__ *types.Package) string {
+		if package__ == package_ {
+			return ""
+		}
+		function_.imports = append(function_.imports, package__.Path())
+		return self.getPackageName(package__)
+	}
+
+	params := signature.Params()
+	for index := 0; index < params.Len(); index++ {
+		if kind := newKind(params.At(index).Type(), qualifier); kind != nil {
+			function_.Parameters = append(function_.Parameters, kind)
+		} else {
+			return nil, fmt.Errorf("%s: unsupported parameter type %s", function.Name(), params.At(index).Type())
+		}
+	}
+
+	results := signature.Results()
+	count := results.Len()
+	if (count > 0) && types.Identical(results.At(count-1).Type(), types.Universe.Lookup("error").Type()) {
+		function_.ReturnsError = true
+		count--
+	}
+
+	switch count {
+	case 0:
+	case 1:
+		if kind := newKind(results.At(0).Type(), qualifier); kind != nil {
+			function_.Result = kind
+		} else {
+			return nil, fmt.Errorf("%s: unsupported result type %s", function.Name(), results.At(0).Type())
+		}
+	default:
+		return nil, fmt.Errorf("%s: multiple results are not supported", function.Name())
+	}
+
+	return &function_, nil
+}
+
+// Avoids conflicts between packages with the same name
+func (self *Generator) getPackageName(package_ *types.Package) string {
+	if name, ok := self.packageNames[package_.Path()]; ok {
+		return name
+	}
+
+	name := package_.Name()
+	for suffix := 2; self.isPackageNameUsed(name); suffix++ {
+		name = fmt.Sprintf("%s%d", package_.Name(), suffix)
+	}
+
+	self.packageNames[package_.Path()] = name
+	return name
+}
+
+func (self *Generator) isPackageNameUsed(name string) bool {
+	for _, name_ := range self.packageNames {
+		if name_ == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *Generator) write(name string, template_ *template.Template) error {
+	var buffer bytes.Buffer
+	if err := template_.Execute(&buffer, self); err != nil {
+		return err
+	}
+
+	code, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return os.WriteFile(filepath.Join(self.Dir, name), code, 0644)
+}
+
+// Used by the templates
+func (self *Generator) PackageName() string {
+	return self.packageName
+}
+
+// Used by the templates
+func (self *Generator) GetFunctions() []*Function {
+	return self.functions
+}
+
+// Used by the templates
+// Returns the import specs for the packages of the named types used by the functions
+func (self *Generator) GetImports() []string {
+	paths := make(map[string]bool)
+	for _, function := range self.functions {
+		for _, path := range function.imports {
+			if !templateImports[path] {
+				paths[path] = true
+			}
+		}
+	}
+
+	var imports []string
+	for path := range paths {
+		if name := self.packageNames[path]; name == filepath.Base(path) {
+			imports = append(imports, strconv.Quote(path))
+		} else {
+			imports = append(imports, name+" "+strconv.Quote(path))
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+//
+// Function
+//
+
+type Function struct {
+	GoName       string
+	PythonName   string
+	GoExport     string
+	PyWrapper    string
+	Parameters   []*Kind
+	Result       *Kind
+	ReturnsError bool
+
+	imports []string
+}
+
+// Used by the templates
+func (self *Function) GoExportDeclaration() string {
+	var params []string
+	for index, kind := range self.Parameters {
+		params = append(params, kind.CDeclaration(fmt.Sprintf("arg%d", index)))
+	}
+	if self.Result != nil {
+		params = append(params, self.Result.CDeclaration("*r"))
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+	return fmt.Sprintf("int %s(%s)", self.GoExport, strings.Join(params, ", "))
+}
+
+//
+// Kind
+//
+
+type Kind struct {
+	GoType    string
+	CgoType   string
+	CType     string
+	BasicKind types.BasicKind
+	Name      string
+}
+
+func newKind(type_ types.Type, qualifier types.Qualifier) *Kind {
+	basic, ok := type_.Underlying().(*types.Basic)
+	if !ok {
+		return nil
+	}
+
+	kind := Kind{
+		GoType:    types.TypeString(type_, qualifier),
+		BasicKind: basic.Kind(),
+		Name:      basic.Name(),
+	}
+
+	switch basic.Kind() {
+	case types.String:
+		kind.CgoType = "*C.char"
+		kind.CType = "char *"
+	case types.Bool:
+		kind.CgoType = "C.int"
+		kind.CType = "int"
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		kind.CgoType = "C.longlong"
+		kind.CType = "long long"
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr:
+		kind.CgoType = "C.ulonglong"
+		kind.CType = "unsigned long long"
+	case types.Float32, types.Float64:
+		kind.CgoType = "C.double"
+		kind.CType = "double"
+	default:
+		return nil
+	}
+
+	return &kind
+}
+
+// Used by the templates
+func (self *Kind) IsString() bool {
+	return self.BasicKind == types.String
+}
+
+// Used by the templates
+func (self *Kind) IsBool() bool {
+	return self.BasicKind == types.Bool
+}
+
+// Used by the templates
+func (self *Kind) IsSigned() bool {
+	switch self.BasicKind {
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		return true
+	}
+	return false
+}
+
+// Used by the templates
+func (self *Kind) IsUnsigned() bool {
+	switch self.BasicKind {
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr:
+		return true
+	}
+	return false
+}
+
+// Used by the templates
+func (self *Kind) IsFloat() bool {
+	switch self.BasicKind {
+	case types.Float32, types.Float64:
+		return true
+	}
+	return false
+}
+
+// Used by the templates
+// Returns the C expression for the valid range (empty if the C type already matches)
+func (self *Kind) RangeCheck(name string) string {
+	switch self.BasicKind {
+	case types.Int:
+		return fmt.Sprintf("(%s < INTPTR_MIN) || (%s > INTPTR_MAX)", name, name)
+	case types.Int8:
+		return fmt.Sprintf("(%s < INT8_MIN) || (%s > INT8_MAX)", name, name)
+	case types.Int16:
+		return fmt.Sprintf("(%s < INT16_MIN) || (%s > INT16_MAX)", name, name)
+	case types.Int32:
+		return fmt.Sprintf("(%s < INT32_MIN) || (%s > INT32_MAX)", name, name)
+	case types.Uint, types.Uintptr:
+		return fmt.Sprintf("%s > UINTPTR_MAX", name)
+	case types.Uint8:
+		return fmt.Sprintf("%s > UINT8_MAX", name)
+	case types.Uint16:
+		return fmt.Sprintf("%s > UINT16_MAX", name)
+	case types.Uint32:
+		return fmt.Sprintf("%s > UINT32_MAX", name)
+	}
+	return ""
+}
+
+// Returns the C declaration of a variable of this kind
+func (self *Kind) CDeclaration(name string) string {
+	if strings.HasSuffix(self.CType, "*") {
+		return self.CType + name
+	} else {
+		return self.CType + " " + name
+	}
+}
+
+// Returns the Go expression that converts the cgo value to the Go type
+func (self *Kind) ToGo(name string) string {
+	var value string
+	switch {
+	case self.IsString():
+		value = fmt.Sprintf("C.GoString(%s)", name)
+	case self.IsBool():
+		value = fmt.Sprintf("%s != 0", name)
+	default:
+		return fmt.Sprintf("%s(%s)", self.GoType, name)
+	}
+
+	if self.GoType == self.Name {
+		return value
+	} else {
+		return fmt.Sprintf("%s(%s)", self.GoType, value)
+	}
+}
+
+// E.g. "HTTPServer" to "http_server"
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for index, rune_ := range runes {
+		if unicode.IsUpper(rune_) {
+			if (index > 0) && ((unicode.IsLower(runes[index-1]) || unicode.IsDigit(runes[index-1])) ||
+				((index+1 < len(runes)) && unicode.IsLower(runes[index+1]) && unicode.IsUpper(runes[index-1]))) {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(unicode.ToLower(rune_))
+		} else {
+			builder.WriteRune(rune_)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,36 @@
+package main
+
+// Generates the cgo wrapper layer that exposes the functions of a Go package to Python
+
+// Usage (e.g. in a "go:generate" comment):
+//   py4go-gen -module api -functions SayGoodbye,Concat
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	var functions string
+
+	generator := NewGenerator()
+	flag.StringVar(&generator.Dir, "dir", ".", "Go package directory")
+	flag.StringVar(&generator.Module, "module", "", "Python module name (defaults to the Go package name)")
+	flag.StringVar(&functions, "functions", "", "comma-separated names of Go functions to expose (defaults to all supported exported functions)")
+	flag.StringVar(&generator.CreateModule, "create", "CreateModule", "name of the generated Go function that creates the module")
+	flag.StringVar(&generator.Prefix, "prefix", "py4go_", "prefix for generated file names")
+	flag.Parse()
+
+	for _, function := range strings.Split(functions, ",") {
+		if function = strings.TrimSpace(function); function != "" {
+			generator.Functions = append(generator.Functions, function)
+		}
+	}
+
+	if err := generator.Generate(); err != nil {
+		fmt.Fprintf(os.Stderr, "py4go-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
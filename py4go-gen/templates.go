@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+var exportTemplate = template.Must(template.New("export").Parse(`// Code generated by py4go-gen. DO NOT EDIT.
+
+package {{ .PackageName }}
+
+// Here we export cgo wrappers for our plain Go functions
+// They handle the conversion between Go and C types
+
+import (
+	"fmt"
+{{- range .GetImports }}
+	{{ . }}
+{{- end }}
+
+	python "github.com/tliron/py4go"
+)
+
+/*
+#cgo pkg-config: python3-embed
+
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+{{ range .GetFunctions }}
+//export {{ .GoExport }}
+func {{ .GoExport }}({{ .GoExportParameters }}) (status C.int) {
+	defer py4goRecover(&status)
+
+{{ .GoExportBody }}}
+{{ end }}
+func py4goRecover(status *C.int) {
+	if recovered := recover(); recovered != nil {
+		python.RaiseGoError(fmt.Errorf("Go panic: %v", recovered))
+		*status = -1
+	}
+}
+`))
+
+var moduleTemplate = template.Must(template.New("module").Parse(`// Code generated by py4go-gen. DO NOT EDIT.
+
+package {{ .PackageName }}
+
+// Here we define Python wrappers in C for our exported "go_" functions and add them to the module
+// They handle the conversion between Python and C types using the _PyCFunctionFast ABI
+
+import (
+	python "github.com/tliron/py4go"
+)
+
+/*
+#cgo pkg-config: python3-embed
+
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+#include <stdint.h>
+{{ range .GetFunctions }}
+{{ .GoExportDeclaration }};
+{{- end }}
+{{ range .GetFunctions }}
+// _PyCFunctionFast signature
+PyObject *{{ .PyWrapper }}(PyObject *self, PyObject *const *args, Py_ssize_t nargs) {
+{{ .PyWrapperBody }}}
+{{ end }}*/
+import "C"
+
+func {{ .CreateModule }}() (*python.Reference, error) {
+	if module, err := python.CreateModule("{{ .Module }}"); err == nil {
+{{- range .GetFunctions }}
+		if err := module.AddModuleCFunctionFastArgs("{{ .PythonName }}", C.{{ .PyWrapper }}); err != nil {
+			module.Release()
+			return nil, err
+		}
+{{ end }}
+		return module, nil
+	} else {
+		return nil, err
+	}
+}
+`))
+
+// Used by the templates
+func (self *Function) GoExportParameters() string {
+	var params []string
+	for index, kind := range self.Parameters {
+		params = append(params, fmt.Sprintf("arg%d %s", index, kind.CgoType))
+	}
+	if self.Result != nil {
+		params = append(params, fmt.Sprintf("r *%s", self.Result.CgoType))
+	}
+	return strings.Join(params, ", ")
+}
+
+// Used by the templates
+func (self *Function) GoExportBody() string {
+	var args []string
+	for index, kind := range self.Parameters {
+		args = append(args, kind.ToGo(fmt.Sprintf("arg%d", index)))
+	}
+	call := fmt.Sprintf("%s(%s)", self.GoName, strings.Join(args, ", "))
+
+	var builder strings.Builder
+	switch {
+	case (self.Result != nil) && self.ReturnsError:
+		fmt.Fprintf(&builder, "\tr_, err := %s\n", call)
+	case self.Result != nil:
+		fmt.Fprintf(&builder, "\tr_ := %s\n", call)
+	case self.ReturnsError:
+		fmt.Fprintf(&builder, "\terr := %s\n", call)
+	default:
+		fmt.Fprintf(&builder, "\t%s\n", call)
+	}
+
+	if self.ReturnsError {
+		builder.WriteString("\tif err != nil {\n\t\tpython.RaiseGoError(err)\n\t\treturn -1\n\t}\n")
+	}
+
+	if self.Result != nil {
+		switch {
+		case self.Result.IsString():
+			if self.Result.GoType == self.Result.Name {
+				builder.WriteString("\t*r = C.CString(r_)\n")
+			} else {
+				builder.WriteString("\t*r = C.CString(string(r_))\n")
+			}
+		case self.Result.IsBool():
+			builder.WriteString("\tif r_ {\n\t\t*r = 1\n\t} else {\n\t\t*r = 0\n\t}\n")
+		default:
+			fmt.Fprintf(&builder, "\t*r = %s(r_)\n", self.Result.CgoType)
+		}
+	}
+
+	builder.WriteString("\treturn 0\n")
+	return builder.String()
+}
+
+// Used by the templates
+func (self *Function) PyWrapperBody() string {
+	var builder strings.Builder
+
+	count := len(self.Parameters)
+	fmt.Fprintf(&builder, "\tif (nargs != %d) {\n", count)
+	fmt.Fprintf(&builder, "\t\tPyErr_Format(PyExc_TypeError, \"%s() takes %d arguments (%%zd given)\", nargs);\n", self.PythonName, count)
+	builder.WriteString("\t\treturn NULL;\n\t}\n")
+
+	var args []string
+	for index, kind := range self.Parameters {
+		arg := fmt.Sprintf("arg%d", index)
+		args = append(args, arg)
+
+		builder.WriteString("\n")
+		switch {
+		case kind.IsString():
+			fmt.Fprintf(&builder, "\tchar *%s = (char *) PyUnicode_AsUTF8(args[%d]);\n", arg, index)
+			fmt.Fprintf(&builder, "\tif (%s == NULL)\n\t\treturn NULL;\n", arg)
+		case kind.IsBool():
+			fmt.Fprintf(&builder, "\tint %s = PyObject_IsTrue(args[%d]);\n", arg, index)
+			fmt.Fprintf(&builder, "\tif (%s == -1)\n\t\treturn NULL;\n", arg)
+		case kind.IsSigned():
+			fmt.Fprintf(&builder, "\tlong long %s = PyLong_AsLongLong(args[%d]);\n", arg, index)
+			fmt.Fprintf(&builder, "\tif ((%s == -1) && PyErr_Occurred())\n\t\treturn NULL;\n", arg)
+		case kind.IsUnsigned():
+			fmt.Fprintf(&builder, "\tunsigned long long %s = PyLong_AsUnsignedLongLong(args[%d]);\n", arg, index)
+			fmt.Fprintf(&builder, "\tif ((%s == (unsigned long long) -1) && PyErr_Occurred())\n\t\treturn NULL;\n", arg)
+		case kind.IsFloat():
+			fmt.Fprintf(&builder, "\tdouble %s = PyFloat_AsDouble(args[%d]);\n", arg, index)
+			fmt.Fprintf(&builder, "\tif ((%s == -1.0) && PyErr_Occurred())\n\t\treturn NULL;\n", arg)
+		}
+
+		if check := kind.RangeCheck(arg); check != "" {
+			fmt.Fprintf(&builder, "\tif (%s) {\n", check)
+			fmt.Fprintf(&builder, "\t\tPyErr_SetString(PyExc_OverflowError, \"%s() argument %d overflows %s\");\n", self.PythonName, index+1, kind.Name)
+			builder.WriteString("\t\treturn NULL;\n\t}\n")
+		}
+	}
+
+	builder.WriteString("\n")
+	if self.Result != nil {
+		if self.Result.IsString() {
+			builder.WriteString("\tchar *r = NULL;\n")
+		} else {
+			fmt.Fprintf(&builder, "\t%s r = 0;\n", self.Result.CType)
+		}
+		args = append(args, "&r")
+	}
+	fmt.Fprintf(&builder, "\tif (%s(%s) != 0)\n\t\treturn NULL;\n", self.GoExport, strings.Join(args, ", "))
+
+	builder.WriteString("\n")
+	if self.Result != nil {
+		switch {
+		case self.Result.IsString():
+			builder.WriteString("\tPyObject *r_ = PyUnicode_FromString(r);\n\tfree(r);\n\treturn r_;\n")
+		case self.Result.IsBool():
+			builder.WriteString("\treturn PyBool_FromLong(r);\n")
+		case self.Result.IsSigned():
+			builder.WriteString("\treturn PyLong_FromLongLong(r);\n")
+		case self.Result.IsUnsigned():
+			builder.WriteString("\treturn PyLong_FromUnsignedLongLong(r);\n")
+		case self.Result.IsFloat():
+			builder.WriteString("\treturn PyFloat_FromDouble(r);\n")
+		}
+	} else {
+		builder.WriteString("\tPy_RETURN_NONE;\n")
+	}
+
+	return builder.String()
+}
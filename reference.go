@@ -1,23 +1,138 @@
 package python
 
+import (
+	"fmt"
+)
+
 /*
 #define PY_SSIZE_T_CLEAN
 #include <Python.h>
+
+static PyInterpreterState *py4go_getCurrentInterpreter() {
+#if PY_VERSION_HEX >= 0x030D0000
+	PyThreadState *state = PyThreadState_GetUnchecked();
+#else
+	PyThreadState *state = _PyThreadState_UncheckedGet();
+#endif
+	return state != NULL ? PyThreadState_GetInterpreter(state) : NULL;
+}
 */
 import "C"
 
+//
+// Ownership
+//
+
+// Functions that return a new *Reference return an Owned reference unless documented otherwise.
+// Functions that steal a reference (e.g. SetTupleItem and SetListItem) leave the argument Stolen.
+type Ownership int
+
+const (
+	// We hold at least one reference, which must be released
+	Owned Ownership = iota
+
+	// Somebody else holds the reference, e.g. the None, True, and False singletons, or items
+	// returned by PyTuple_GetItem. Releasing it is a no-op.
+	Borrowed
+
+	// The reference was handed over to a function that steals it. Releasing it is a no-op.
+	Stolen
+
+	// All the references we held were released
+	Released
+)
+
+// fmt.Stringer interface
+func (self Ownership) String() string {
+	switch self {
+	case Owned:
+		return "owned"
+	case Borrowed:
+		return "borrowed"
+	case Stolen:
+		return "stolen"
+	case Released:
+		return "released"
+	default:
+		return fmt.Sprintf("Ownership(%d)", int(self))
+	}
+}
+
 //
 // Reference
 //
 
 type Reference struct {
 	Object *C.PyObject
+
+	ownership   Ownership
+	count       int                   // number of references we hold
+	interpreter *C.PyInterpreterState // nil if unknown
 }
 
+// Wraps a new (owned) reference, which should eventually be released
 func NewReference(pyObject *C.PyObject) *Reference {
-	return &Reference{pyObject}
+	self := &Reference{Object: pyObject, ownership: Owned, count: 1, interpreter: getCurrentInterpreter()}
+	self.setFinalizer()
+	self.track()
+	return self
+}
+
+// Wraps a borrowed reference. Releasing it is a no-op unless it was acquired.
+func NewBorrowedReference(pyObject *C.PyObject) *Reference {
+	return &Reference{Object: pyObject, ownership: Borrowed}
+}
+
+func (self *Reference) Ownership() Ownership {
+	return self.ownership
 }
 
 func (self *Reference) Type() *Type {
 	return NewType(self.Object.ob_type)
 }
+
+// Prepares the reference for a function that steals it: a borrowed reference is acquired first,
+// and an owned reference gives up one of its references. Returns the object.
+func (self *Reference) steal() *C.PyObject {
+	if self.count > 0 {
+		self.count--
+		if self.count == 0 {
+			self.ownership = Stolen
+			self.clearFinalizer()
+			self.untrack()
+		}
+	} else {
+		if self.ownership == Released {
+			panicIfDebug("stealing released reference")
+		}
+		C.Py_IncRef(self.Object)
+	}
+	return self.Object
+}
+
+// Returns ErrWrongInterpreter if any of the references belongs to an interpreter other than the
+// current one (see Interpreter). Nil references and those of unknown interpreters are ignored.
+func checkInterpreters(references ...*Reference) error {
+	var current *C.PyInterpreterState
+	for _, reference := range references {
+		if (reference != nil) && (reference.interpreter != nil) {
+			if current == nil {
+				current = getCurrentInterpreter()
+			}
+			if reference.interpreter != current {
+				return ErrWrongInterpreter
+			}
+		}
+	}
+	return nil
+}
+
+func getCurrentInterpreter() *C.PyInterpreterState {
+	return C.py4go_getCurrentInterpreter()
+}
+
+func panicIfDebug(message string) {
+	if debug {
+		panic(message)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build py4go_debug
+// +build py4go_debug
+
+package python
+
+import (
+	"testing"
+)
+
+func TestReferenceDoubleRelease(t *testing.T) {
+	withGIL(t, func() {
+		object := eval(t, "object()", nil)
+		object.Release()
+
+		defer func() {
+			if recovered := recover(); recovered != "double release of reference" {
+				t.Errorf("expected a double release panic, got %v", recovered)
+			}
+		}()
+
+		object.Release()
+	})
+}
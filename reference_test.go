@@ -0,0 +1,102 @@
+package python
+
+import (
+	"testing"
+)
+
+func TestReferenceOwned(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newGlobals(t, nil)
+		defer globals.Release()
+
+		if err := Exec("import weakref\nclass C: pass\nc = C()\nr = weakref.ref(c)\n", globals, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		object := getGlobal(t, globals, "c")
+		if object.Ownership() != Owned {
+			t.Errorf("expected owned, got %s", object.Ownership())
+		}
+
+		if err := Exec("del c", globals, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// We hold two references now
+		object.Acquire()
+		object.Release()
+		assertPython(t, globals, "value['r']() is not None")
+		if object.Ownership() != Owned {
+			t.Errorf("expected owned, got %s", object.Ownership())
+		}
+
+		// The last reference frees the object
+		object.Release()
+		assertPython(t, globals, "value['r']() is None")
+		if object.Ownership() != Released {
+			t.Errorf("expected released, got %s", object.Ownership())
+		}
+	})
+}
+
+func TestReferenceBorrowed(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		if None.Ownership() != Borrowed {
+			t.Errorf("expected borrowed, got %s", None.Ownership())
+		}
+
+		// A no-op
+		None.Release()
+		if None.Ownership() != Borrowed {
+			t.Errorf("expected borrowed, got %s", None.Ownership())
+		}
+
+		// Acquiring makes it owned
+		none := NewBorrowedReference(None.Object)
+		none.Acquire()
+		if none.Ownership() != Owned {
+			t.Errorf("expected owned, got %s", none.Ownership())
+		}
+		none.Release()
+		if none.Ownership() != Released {
+			t.Errorf("expected released, got %s", none.Ownership())
+		}
+	})
+}
+
+func TestReferenceStolen(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		tuple, err := NewTupleRaw(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tuple.Release()
+
+		item := eval(t, "'item'", nil)
+		if err := tuple.SetTupleItem(0, item); err != nil {
+			t.Fatal(err)
+		}
+		if item.Ownership() != Stolen {
+			t.Errorf("expected stolen, got %s", item.Ownership())
+		}
+
+		// A no-op
+		item.Release()
+
+		// Borrowed items are acquired before they are stolen
+		if err := tuple.SetTupleItem(1, None); err != nil {
+			t.Fatal(err)
+		}
+		if None.Ownership() != Borrowed {
+			t.Errorf("expected borrowed, got %s", None.Ownership())
+		}
+
+		assertPython(t, tuple, "value == ('item', None)")
+	})
+}
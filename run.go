@@ -0,0 +1,212 @@
+package python
+
+// See:
+//   https://docs.python.org/3/library/runpy.html
+//   https://docs.python.org/3/library/sys.html#sys.argv
+//   https://docs.python.org/3/library/exceptions.html#SystemExit
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+// Runs a Python file (or a directory or zip file with a __main__.py) as a script, like
+// "python path args...". The script's directory is prepended to sys.path while it runs.
+//
+// sys.argv is restored afterwards. If the script raises SystemExit with a non-zero code the error
+// is an *ExitError. Other exceptions are returned as is.
+func RunFile(path string, argv []string) error {
+	if path_, err := filepath.Abs(path); err == nil {
+		path = path_
+	} else {
+		return err
+	}
+
+	return withSysArgv(path, argv, func() error {
+		// For a directory or zip file runpy adds the path itself to sys.path
+		if info, err := os.Stat(path); (err == nil) && !info.IsDir() && !isZipFile(path) {
+			directory := filepath.Dir(path)
+			if err := InsertSysPath(0, directory); err != nil {
+				return err
+			}
+			defer removeSysPathHead(directory)
+		}
+
+		return callRunpy("run_path", path, nil, "__main__")
+	})
+}
+
+// Runs a Python module as a script, like "python -m name args...". The module must be importable
+// from the current sys.path.
+//
+// sys.argv is restored afterwards. If the module raises SystemExit with a non-zero code the error
+// is an *ExitError. Other exceptions are returned as is.
+func RunModule(name string, argv []string) error {
+	return withSysArgv(name, argv, func() error {
+		return callRunpy("run_module", name, nil, "__main__", true)
+	})
+}
+
+//
+// ExitError
+//
+
+// A Python SystemExit with a non-zero code
+type ExitError struct {
+	Code int
+
+	// Set if SystemExit was raised with a non-integer code, e.g. sys.exit("bad input"), in which
+	// case Code is 1
+	Message string
+
+	Exception *Exception
+}
+
+// error signature
+func (self *ExitError) Error() string {
+	if self.Message != "" {
+		return fmt.Sprintf("Python exited with code %d: %s", self.Code, self.Message)
+	} else {
+		return fmt.Sprintf("Python exited with code %d", self.Code)
+	}
+}
+
+// errors.Unwrap signature
+func (self *ExitError) Unwrap() error {
+	return self.Exception
+}
+
+// Returns nil for a successful exit, like the Python interpreter would, in which case the exception
+// is released
+func newExitError(exception *Exception) error {
+	if exception.Value == nil {
+		exception.Release()
+		return nil
+	}
+
+	code := getAttrRaw(exception.Value.Object, "code")
+	if code == nil {
+		exception.Release()
+		return nil
+	}
+	defer C.Py_DecRef(code)
+
+	if code == C.Py_None {
+		exception.Release()
+		return nil
+	}
+
+	if code_ := NewBorrowedReference(code); code_.IsLong() {
+		if int_, err := code_.ToInt64(); err == nil {
+			if int_ == 0 {
+				exception.Release()
+				return nil
+			}
+			return &ExitError{Code: int(int_), Exception: exception}
+		} else {
+			exception.Release()
+			return err
+		}
+	} else {
+		return &ExitError{Code: 1, Message: code_.String(), Exception: exception}
+	}
+}
+
+func callRunpy(name string, args ...interface{}) error {
+	if runpy, err := Import("runpy"); err == nil {
+		defer runpy.Release()
+
+		if run, err := runpy.GetAttr(name); err == nil {
+			defer run.Release()
+
+			if r, err := run.Call(args...); err == nil {
+				r.Release()
+				return nil
+			} else {
+				if exception, ok := err.(*Exception); ok && exception.Matches(ExcSystemExit) {
+					return newExitError(exception)
+				}
+				return err
+			}
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Sets sys.argv for the duration of the function, restoring the original afterwards
+func withSysArgv(argv0 string, argv []string, f func() error) error {
+	argv_ := C.CString("argv")
+	defer C.free(unsafe.Pointer(argv_))
+
+	// Borrowed reference
+	var original *Reference
+	if original_ := C.PySys_GetObject(argv_); original_ != nil {
+		original = NewBorrowedReference(original_)
+		original.Acquire()
+		defer original.Release()
+	}
+
+	items := make([]interface{}, len(argv)+1)
+	items[0] = argv0
+	for index, arg := range argv {
+		items[index+1] = arg
+	}
+
+	if list, err := NewList(items...); err == nil {
+		defer list.Release()
+
+		if C.PySys_SetObject(argv_, list.Object) != 0 {
+			return GetError()
+		}
+	} else {
+		return err
+	}
+
+	defer func() {
+		// Deletes sys.argv if there was no original
+		var original_ *C.PyObject
+		if original != nil {
+			original_ = original.Object
+		}
+		C.PySys_SetObject(argv_, original_)
+	}()
+
+	return f()
+}
+
+func isZipFile(path string) bool {
+	if reader, err := zip.OpenReader(path); err == nil {
+		reader.Close()
+		return true
+	} else {
+		return false
+	}
+}
+
+// Removes the first sys.path entry if it's the path, e.g. if the script didn't remove it itself
+func removeSysPathHead(path string) {
+	if sysPath, err := getSysPath(); err == nil {
+		if C.PyList_Size(sysPath.Object) > 0 {
+			// Borrowed reference
+			if head, err := NewBorrowedReference(C.PyList_GetItem(sysPath.Object, 0)).ToString(); (err == nil) && (head == path) {
+				if C.PySequence_DelItem(sysPath.Object, 0) == 0 {
+					InvalidateImportCaches()
+					return
+				}
+			}
+		}
+	}
+	C.PyErr_Clear()
+}
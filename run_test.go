@@ -0,0 +1,102 @@
+package python
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestScript(t *testing.T, source string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script.py")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunFileExitCodes(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, test := range []struct {
+			source  string
+			code    int
+			message string
+		}{
+			{"x = 1\n", 0, ""},
+			{"import sys\nsys.exit()\n", 0, ""},
+			{"import sys\nsys.exit(0)\n", 0, ""},
+			{"import sys\nsys.exit(3)\n", 3, ""},
+			{"raise SystemExit(-2)\n", -2, ""},
+			{"import sys\nsys.exit('bad input')\n", 1, "bad input"},
+		} {
+			err := RunFile(writeTestScript(t, test.source), nil)
+
+			if test.code == 0 {
+				if err != nil {
+					t.Errorf("%q: unexpected error %v", test.source, err)
+				}
+				continue
+			}
+
+			var exitError *ExitError
+			if errors.As(err, &exitError) {
+				if (exitError.Code != test.code) || (exitError.Message != test.message) {
+					t.Errorf("%q: expected code %d and message %q, got %d and %q", test.source, test.code, test.message, exitError.Code, exitError.Message)
+				}
+				if !errors.Is(err, ErrSystemExit) {
+					t.Errorf("%q: expected errors.Is to match SystemExit", test.source)
+				}
+			} else {
+				t.Errorf("%q: expected an *ExitError, got %v", test.source, err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+func TestRunFileException(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		err := RunFile(writeTestScript(t, "raise ValueError('bad')\n"), nil)
+
+		var exitError *ExitError
+		if errors.As(err, &exitError) || !errors.Is(err, ErrValueError) {
+			t.Errorf("expected ValueError, got %v", err)
+		}
+		releaseError(err)
+	})
+}
+
+func TestRunFileArgv(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		original := eval(t, "__import__('sys').argv", nil)
+		defer original.Release()
+
+		path := writeTestScript(t, `
+import os, sys
+if __name__ != '__main__':
+    sys.exit('bad __name__: %s' % __name__)
+if sys.argv[1:] != ['a', 'b']:
+    sys.exit('bad argv: %s' % sys.argv)
+if sys.path[0] != os.path.dirname(os.path.abspath(__file__)):
+    sys.exit('bad path: %s' % sys.path[0])
+`)
+
+		if err := RunFile(path, []string{"a", "b"}); err != nil {
+			t.Error(err)
+		}
+
+		// Restored afterwards
+		assertPython(t, original, "value is __import__('sys').argv")
+		if contains, err := SysPathContains(filepath.Dir(path)); (err != nil) || contains {
+			t.Errorf("expected the script's directory to be removed from sys.path: %v", err)
+		}
+	})
+}
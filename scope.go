@@ -0,0 +1,80 @@
+package python
+
+import (
+	"sync"
+)
+
+//
+// Scope
+//
+
+// Tracks references and releases them all together, like an arena. Useful for avoiding leaks on
+// error paths:
+//
+//	scope := python.NewScope()
+//	defer scope.Close()
+//
+//	hello, err := scope.Track(module.GetAttr("hello"))
+//	if err != nil {
+//		return err
+//	}
+//	r, err := scope.Track(hello.Call("Tal"))
+//
+// A scope is safe for concurrent use, though the references must of course be released while
+// holding the GIL.
+type Scope struct {
+	references []*Reference
+	lock       sync.Mutex
+}
+
+func NewScope() *Scope {
+	return new(Scope)
+}
+
+// Adds the reference to the scope and returns it. Nil references are ignored.
+func (self *Scope) Add(reference *Reference) *Reference {
+	if reference != nil {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+
+		self.references = append(self.references, reference)
+	}
+	return reference
+}
+
+// Adds the reference to the scope if there is no error. Meant to wrap calls that return a new
+// reference, e.g. scope.Track(module.GetAttr("hello")).
+func (self *Scope) Track(reference *Reference, err error) (*Reference, error) {
+	if err == nil {
+		self.Add(reference)
+	}
+	return reference, err
+}
+
+// Removes the reference from the scope without releasing it, e.g. when you want to return it.
+// Returns the reference.
+func (self *Scope) Keep(reference *Reference) *Reference {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for index := len(self.references) - 1; index >= 0; index-- {
+		if self.references[index] == reference {
+			self.references = append(self.references[:index], self.references[index+1:]...)
+			break
+		}
+	}
+	return reference
+}
+
+// Releases all references in the reverse order in which they were added. The scope can be reused
+// afterwards.
+func (self *Scope) Close() {
+	self.lock.Lock()
+	references := self.references
+	self.references = nil
+	self.lock.Unlock()
+
+	for index := len(references) - 1; index >= 0; index-- {
+		references[index].Release()
+	}
+}
@@ -0,0 +1,64 @@
+package python
+
+import (
+	"errors"
+	"testing"
+)
+
+const scopeSource = `
+deleted = []
+
+class C:
+    def __init__(self, name):
+        self.name = name
+
+    def __del__(self):
+        deleted.append(self.name)
+`
+
+func TestScope(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		globals := newGlobals(t, nil)
+		defer globals.Release()
+
+		if err := Exec(scopeSource, globals, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		class := getGlobal(t, globals, "C")
+		defer class.Release()
+
+		scope := NewScope()
+
+		for _, name := range []string{"a", "b", "c"} {
+			if _, err := scope.Track(class.Call(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		kept, err := scope.Track(class.Call("kept"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scope.Keep(kept)
+
+		// Not added
+		if reference, err := scope.Track(nil, errors.New("failed")); (reference != nil) || (err == nil) {
+			t.Error("expected the error")
+		}
+		scope.Add(nil)
+
+		// Released in reverse order
+		scope.Close()
+		assertPython(t, globals, "value['deleted'] == ['c', 'b', 'a']")
+
+		kept.Release()
+		assertPython(t, globals, "value['deleted'] == ['c', 'b', 'a', 'kept']")
+
+		// Reusable
+		scope.Add(eval(t, "object()", nil))
+		scope.Close()
+	})
+}
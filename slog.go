@@ -0,0 +1,693 @@
+//go:build go1.21
+// +build go1.21
+
+package python
+
+// See:
+//   https://docs.python.org/3/library/logging.html
+//   https://pkg.go.dev/log/slog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+// Our Handler and Logger classes subclass Python's, which Go classes cannot do (see NewGoClass), so
+// we create them via type() and add Go functions as their methods. The slog handler is kept in a Go
+// object in this attribute.
+const loggingHandlerAttribute = "_py4go_handler"
+
+// Creates a Python logging.Handler that forwards records to the slog handler. Use the logger's
+// addHandler method to attach it, or see AddLoggingHandler.
+//
+// Levels are mapped such that DEBUG, INFO, WARNING, and ERROR become their slog equivalents
+// (CRITICAL becomes ERROR+4). The record's logger name becomes the "logger" attribute, its
+// pathname, lineno, and funcName become the "source" attribute, and formatted exception info and
+// stack info become the "exception" and "stack" attributes. Fields added via "extra" become
+// attributes of their own, with values that are not primitives, lists, or dicts converted to
+// strings.
+//
+// The slog handler is called while holding the GIL.
+func NewLoggingHandler(handler slog.Handler) (*Reference, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	// Borrowed reference
+	handlerClass, err := getLoggingClass("Handler", map[string]interface{}{
+		"__repr__": reprLoggingHandler,
+		"handle":   handleLoggingRecord,
+		"emit":     emitLoggingRecord,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := getHelperGoClass("py4go.SlogHandler", (*slogHandler)(nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	handler_, err := scope.Track(class.NewObject(&slogHandler{handler}))
+	if err != nil {
+		return nil, err
+	}
+
+	if self, err := handlerClass.Call(); err == nil {
+		if err := self.SetAttr(loggingHandlerAttribute, handler_); err == nil {
+			return self, nil
+		} else {
+			self.Release()
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Creates a handler with NewLoggingHandler and adds it to the named Python logger. An empty name
+// is the root logger.
+//
+// Note that the Python logger's level still applies (for the root logger it is WARNING by
+// default).
+func AddLoggingHandler(name string, handler slog.Handler) (*Reference, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	logger, err := scope.Track(getLogger(name))
+	if err != nil {
+		return nil, err
+	}
+
+	handler_, err := NewLoggingHandler(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := callLoggingMethod(logger, "addHandler", handler_); err == nil {
+		return handler_, nil
+	} else {
+		handler_.Release()
+		return nil, err
+	}
+}
+
+// Creates a Python logging.Logger that logs to the slog logger. Its level is determined by the
+// slog logger's handler. It is not registered with logging.getLogger and does not propagate to
+// the root logger.
+func NewGoLogger(name string, logger *slog.Logger) (*Reference, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	// Borrowed reference
+	loggerClass, err := getLoggingClass("Logger", map[string]interface{}{
+		"__repr__":     reprGoLogger,
+		"isEnabledFor": isGoLoggerEnabledFor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := scope.Track(NewLoggingHandler(logger.Handler()))
+	if err != nil {
+		return nil, err
+	}
+
+	if self, err := loggerClass.Call(name); err == nil {
+		if err := self.SetAttr("propagate", False); err != nil {
+			self.Release()
+			return nil, err
+		}
+
+		if err := callLoggingMethod(self, "addHandler", handler); err != nil {
+			self.Release()
+			return nil, err
+		}
+
+		if err := self.SetAttr(loggingHandlerAttribute, handler); err != nil {
+			self.Release()
+			return nil, err
+		}
+
+		return self, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Python's levels are 10 apart while slog's are 4 apart, with INFO at 20 and 0 respectively
+func toSlogLevel(level int) slog.Level {
+	return slog.Level((level - 20) * 2 / 5)
+}
+
+func getLogger(name string) (*Reference, error) {
+	if name == "" {
+		return callModuleAttr("logging", "getLogger", nil, nil)
+	} else {
+		return callModuleAttr("logging", "getLogger", []interface{}{name}, nil)
+	}
+}
+
+// Returns a borrowed reference to our subclass of the logging class with the same name
+func getLoggingClass(name string, methods map[string]interface{}) (*Reference, error) {
+	return getInterpreterCached("py4go.logging."+name, func() (*Reference, error) {
+		scope := NewScope()
+		defer scope.Close()
+
+		base, err := scope.Track(getLoggingAttr(name))
+		if err != nil {
+			return nil, err
+		}
+
+		// type(name, (base,), {"__module__": "py4go"})
+		class, err := callModuleAttr("builtins", "type", []interface{}{name, [1]*Reference{base}, map[string]interface{}{"__module__": "py4go"}}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for name_, method := range methods {
+			if function, err := scope.Track(NewGoFunction("py4go."+name+"."+name_, method)); err == nil {
+				if err := addInstanceMethod(class, name_, function); err != nil {
+					class.Release()
+					return nil, err
+				}
+			} else {
+				class.Release()
+				return nil, err
+			}
+		}
+
+		return class, nil
+	})
+}
+
+func getLoggingAttr(name string) (*Reference, error) {
+	if logging, err := Import("logging"); err == nil {
+		defer logging.Release()
+
+		return logging.GetAttr(name)
+	} else {
+		return nil, err
+	}
+}
+
+// Calls the method of the logging class's base class (like super() does)
+func callLoggingBaseMethod(class string, name string, self *Reference, args ...interface{}) (*Reference, error) {
+	if class_, err := getLoggingAttr(class); err == nil {
+		defer class_.Release()
+
+		if method, err := class_.GetAttr(name); err == nil {
+			defer method.Release()
+
+			return method.Call(append([]interface{}{self}, args...)...)
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Calls the method and discards the result
+func callLoggingMethod(self *Reference, name string, args ...interface{}) error {
+	if method, err := self.GetAttr(name); err == nil {
+		defer method.Release()
+
+		if r, err := method.Call(args...); err == nil {
+			r.Release()
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+func unmarshalLoggingAttr(self *Reference, name string, dest interface{}) error {
+	if attr, err := self.GetAttr(name); err == nil {
+		defer attr.Release()
+
+		return attr.Unmarshal(dest)
+	} else {
+		return err
+	}
+}
+
+// The returned reference belongs to the scope. Returns nil if the attribute is None.
+func getLoggingAttrIfNotNone(scope *Scope, self *Reference, name string) (*Reference, error) {
+	if attr, err := scope.Track(self.GetAttr(name)); err == nil {
+		if attr.Object == None.Object {
+			return nil, nil
+		}
+		return attr, nil
+	} else {
+		return nil, err
+	}
+}
+
+//
+// Handler methods
+//
+
+func reprLoggingHandler(self *Reference) (string, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	level, err := scope.Track(self.GetAttr("level"))
+	if err != nil {
+		return "", err
+	}
+
+	if name, err := scope.Track(callModuleAttr("logging", "getLevelName", []interface{}{level}, nil)); err == nil {
+		if name_, err := name.Str(); err == nil {
+			defer name_.Release()
+			return "<py4go.Handler (" + name_.String() + ")>", nil
+		} else {
+			return "", err
+		}
+	} else {
+		return "", err
+	}
+}
+
+// Checks the slog handler's level before calling logging.Handler.handle, which applies the filters
+func handleLoggingRecord(self *Reference, record *Reference) (*Reference, error) {
+	if handler, err := getSlogHandler(self); err == nil {
+		var level int
+		if err := unmarshalLoggingAttr(record, "levelno", &level); err != nil {
+			return nil, err
+		}
+
+		if !handler.Enabled(context.Background(), toSlogLevel(level)) {
+			// The returned reference is stolen
+			return newAcquiredReference(False.Object), nil
+		}
+
+		return callLoggingBaseMethod("Handler", "handle", self, record)
+	} else {
+		return nil, err
+	}
+}
+
+// Like logging.Handler implementations, errors other than RecursionError are reported via
+// handleError
+func emitLoggingRecord(self *Reference, record *Reference) error {
+	err := func() error {
+		if handler, err := getSlogHandler(self); err == nil {
+			if record_, err := newSlogRecord(record); err == nil {
+				return handler.Handle(context.Background(), record_)
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+	}()
+
+	if (err == nil) || errors.Is(err, ErrRecursionError) {
+		return err
+	}
+
+	// handleError expects a traceback, so we add the current frame, as if the error was raised here
+	raiseReturnedError(err)
+	if frame := C.PyEval_GetFrame(); frame != nil {
+		C.PyTraceBack_Here(frame)
+	}
+	exception := FetchException()
+	defer exception.Release()
+
+	return exception.handle(func() error {
+		return callLoggingMethod(self, "handleError", record)
+	})
+}
+
+func getSlogHandler(self *Reference) (slog.Handler, error) {
+	if handler, err := self.GetAttr(loggingHandlerAttribute); err == nil {
+		defer handler.Release()
+
+		if handler_, err := handler.ToGoObject(); err == nil {
+			if handler__, ok := handler_.(*slogHandler); ok {
+				return handler__.handler, nil
+			}
+		}
+	} else {
+		return nil, err
+	}
+
+	return nil, newTypeError("%s is not a py4go.SlogHandler", loggingHandlerAttribute)
+}
+
+func newSlogRecord(record *Reference) (slog.Record, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	var level int
+	var name, pathname string
+	var created float64
+	var lineno int
+	var function interface{}
+	for attr, dest := range map[string]interface{}{
+		"levelno":  &level,
+		"name":     &name,
+		"created":  &created,
+		"pathname": &pathname,
+		"lineno":   &lineno,
+		"funcName": &function,
+	} {
+		if err := unmarshalLoggingAttr(record, attr, dest); err != nil {
+			return slog.Record{}, err
+		}
+	}
+
+	var message string
+	if getMessage, err := scope.Track(record.GetAttr("getMessage")); err == nil {
+		if message_, err := scope.Track(getMessage.Call()); err == nil {
+			if err := message_.Unmarshal(&message); err != nil {
+				return slog.Record{}, err
+			}
+		} else {
+			return slog.Record{}, err
+		}
+	} else {
+		return slog.Record{}, err
+	}
+
+	seconds, fraction := math.Modf(created)
+	record_ := slog.NewRecord(time.Unix(int64(seconds), int64(fraction*1e9)), toSlogLevel(level), message, 0)
+
+	record_.AddAttrs(slog.String("logger", name))
+
+	if pathname != "" {
+		function_, _ := function.(string)
+		record_.AddAttrs(slog.Any(slog.SourceKey, &slog.Source{
+			Function: function_,
+			File:     pathname,
+			Line:     lineno,
+		}))
+	}
+
+	if exception, err := formatLoggingException(scope, record); err == nil {
+		if exception != "" {
+			record_.AddAttrs(slog.String("exception", exception))
+		}
+	} else {
+		return slog.Record{}, err
+	}
+
+	if stack, err := formatLoggingStack(scope, record); err == nil {
+		if stack != "" {
+			record_.AddAttrs(slog.String("stack", stack))
+		}
+	} else {
+		return slog.Record{}, err
+	}
+
+	if extra, err := getLoggingExtra(scope, record); err == nil {
+		keys := make([]string, 0, len(extra))
+		for key := range extra {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			record_.AddAttrs(slog.Any(key, extra[key]))
+		}
+	} else {
+		return slog.Record{}, err
+	}
+
+	return record_, nil
+}
+
+// Formats exc_info, or else returns exc_text (like logging.Formatter.format)
+func formatLoggingException(scope *Scope, record *Reference) (string, error) {
+	if excInfo, err := getLoggingAttrIfNotNone(scope, record, "exc_info"); err == nil {
+		if excInfo != nil {
+			return callLoggingFormatter(scope, "formatException", excInfo)
+		}
+	} else {
+		return "", err
+	}
+
+	if excText, err := getLoggingAttrIfNotNone(scope, record, "exc_text"); err == nil {
+		if excText != nil {
+			var excText_ string
+			err := excText.Unmarshal(&excText_)
+			return excText_, err
+		}
+		return "", nil
+	} else {
+		return "", err
+	}
+}
+
+func formatLoggingStack(scope *Scope, record *Reference) (string, error) {
+	if stackInfo, err := getLoggingAttrIfNotNone(scope, record, "stack_info"); err == nil {
+		if stackInfo != nil {
+			return callLoggingFormatter(scope, "formatStack", stackInfo)
+		}
+		return "", nil
+	} else {
+		return "", err
+	}
+}
+
+// Calls the method of a default logging.Formatter
+func callLoggingFormatter(scope *Scope, name string, arg *Reference) (string, error) {
+	formatter, err := getInterpreterCached("py4go.logging.formatter", func() (*Reference, error) {
+		return callModuleAttr("logging", "Formatter", nil, nil)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	method, err := scope.Track(formatter.GetAttr(name))
+	if err != nil {
+		return "", err
+	}
+
+	if formatted, err := scope.Track(method.Call(arg)); err == nil {
+		var formatted_ string
+		err := formatted.Unmarshal(&formatted_)
+		return formatted_, err
+	} else {
+		return "", err
+	}
+}
+
+// The record's attributes other than the standard ones (i.e. those added via "extra") and those
+// starting with "_"
+func getLoggingExtra(scope *Scope, record *Reference) (map[string]interface{}, error) {
+	standard, err := getInterpreterCached("py4go.logging.standard", newStandardLoggingAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	dict, err := scope.Track(record.GetAttr("__dict__"))
+	if err != nil {
+		return nil, err
+	}
+	if !dict.IsDict() {
+		return nil, newTypeError("record __dict__ is not a dict")
+	}
+
+	extra := make(map[string]interface{})
+	// Borrowed references
+	for _, item := range getDictItems(dict) {
+		if !item[0].IsUnicode() {
+			continue
+		}
+
+		if key, err := item[0].ToString(); err == nil {
+			if strings.HasPrefix(key, "_") {
+				continue
+			}
+
+			switch C.PySet_Contains(standard.Object, item[0].Object) {
+			case 0:
+				if value, err := toSlogValue(item[1]); err == nil {
+					extra[key] = value
+				} else {
+					return nil, err
+				}
+			case -1:
+				return nil, GetError()
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	return extra, nil
+}
+
+// Returns a frozenset of the attribute names of a plain logging.LogRecord plus those added by
+// logging.Formatter
+func newStandardLoggingAttributes() (*Reference, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	// logging.LogRecord('', 0, '', 0, '', (), None)
+	record, err := scope.Track(callModuleAttr("logging", "LogRecord", []interface{}{"", 0, "", 0, "", [0]interface{}{}, nil}, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	dict, err := scope.Track(record.GetAttr("__dict__"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{"message", "asctime"}
+	// Borrowed references
+	for _, item := range getDictItems(dict) {
+		if name, err := item[0].ToString(); err == nil {
+			names = append(names, name)
+		} else {
+			return nil, err
+		}
+	}
+
+	return callModuleAttr("builtins", "frozenset", []interface{}{names}, nil)
+}
+
+// Converts primitives, lists, tuples, and dicts (with their keys converted to strings). Anything
+// else, including ints that do not fit in an int64, is converted to a string.
+func toSlogValue(value *Reference) (interface{}, error) {
+	switch {
+	case value.Object == None.Object:
+		return nil, nil
+
+	case value.IsBool():
+		return value.ToBool(), nil
+
+	case value.IsLong():
+		var value_ int64
+		if err := value.Unmarshal(&value_); err == nil {
+			return value_, nil
+		}
+
+	case value.IsFloat():
+		return value.ToFloat64()
+
+	case value.IsUnicode():
+		return value.ToString()
+
+	case value.IsDict():
+		dict := make(map[string]interface{})
+		// Borrowed references
+		for _, item := range getDictItems(value) {
+			if key, err := toSlogString(item[0]); err == nil {
+				if value_, err := toSlogValue(item[1]); err == nil {
+					dict[key] = value_
+				} else {
+					return nil, err
+				}
+			} else {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+
+	// Borrowed references
+	if items, ok := getSequenceItems(value); ok {
+		list := make([]interface{}, len(items))
+		for index, item := range items {
+			if item_, err := toSlogValue(item); err == nil {
+				list[index] = item_
+			} else {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+
+	return toSlogString(value)
+}
+
+// Like Python's str()
+func toSlogString(value *Reference) (string, error) {
+	if str, err := value.Str(); err == nil {
+		defer str.Release()
+
+		return str.ToString()
+	} else {
+		return "", err
+	}
+}
+
+//
+// Logger methods
+//
+
+func reprGoLogger(self *Reference) (string, error) {
+	var name interface{}
+	if err := unmarshalLoggingAttr(self, "name", &name); err == nil {
+		name_, _ := name.(string)
+		return "<py4go.Logger " + name_ + ">", nil
+	} else {
+		return "", err
+	}
+}
+
+// Like logging.Logger.isEnabledFor but determined by the slog handler (and not cached)
+func isGoLoggerEnabledFor(self *Reference, level int) (bool, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	var disabled bool
+	if err := unmarshalLoggingAttr(self, "disabled", &disabled); err != nil {
+		return false, err
+	}
+	if disabled {
+		return false, nil
+	}
+
+	manager, err := scope.Track(self.GetAttr("manager"))
+	if err != nil {
+		return false, err
+	}
+
+	var disable int
+	if err := unmarshalLoggingAttr(manager, "disable", &disable); err != nil {
+		return false, err
+	}
+	if disable >= level {
+		return false, nil
+	}
+
+	handler, err := scope.Track(self.GetAttr(loggingHandlerAttribute))
+	if err != nil {
+		return false, err
+	}
+
+	if handler_, err := getSlogHandler(handler); err == nil {
+		return handler_.Enabled(context.Background(), toSlogLevel(level)), nil
+	} else {
+		return false, err
+	}
+}
+
+//
+// slogHandler
+//
+
+type slogHandler struct {
+	handler slog.Handler
+}
+
+// fmt.Stringer interface
+func (self *slogHandler) String() string {
+	return "<py4go.SlogHandler>"
+}
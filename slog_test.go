@@ -0,0 +1,268 @@
+//go:build go1.21
+// +build go1.21
+
+package python
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Collects the records it handles
+type testSlogHandler struct {
+	level   slog.Level
+	err     error
+	records []slog.Record
+}
+
+// slog.Handler interface
+func (self *testSlogHandler) Enabled(context context.Context, level slog.Level) bool {
+	return level >= self.level
+}
+
+// slog.Handler interface
+func (self *testSlogHandler) Handle(context context.Context, record slog.Record) error {
+	if self.err != nil {
+		return self.err
+	}
+	self.records = append(self.records, record)
+	return nil
+}
+
+// slog.Handler interface
+func (self *testSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return self
+}
+
+// slog.Handler interface
+func (self *testSlogHandler) WithGroup(name string) slog.Handler {
+	return self
+}
+
+func getSlogAttrs(record slog.Record) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value.Any()
+		return true
+	})
+	return attrs
+}
+
+func execLogging(t *testing.T, logger *Reference, code string) {
+	t.Helper()
+
+	globals := newGlobals(t, map[string]interface{}{"logger": logger})
+	defer globals.Release()
+
+	if err := Exec("import logging\n"+code, globals, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToSlogLevel(t *testing.T) {
+	for level, expected := range map[int]slog.Level{
+		10: slog.LevelDebug,
+		20: slog.LevelInfo,
+		30: slog.LevelWarn,
+		40: slog.LevelError,
+		50: slog.LevelError + 4,
+	} {
+		if level_ := toSlogLevel(level); level_ != expected {
+			t.Errorf("%d: expected %s, got %s", level, expected, level_)
+		}
+	}
+}
+
+func TestNewGoLogger(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		handler := &testSlogHandler{level: slog.LevelInfo}
+		logger, err := NewGoLogger("py4go.test", slog.New(handler))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer logger.Release()
+
+		assertPython(t, logger, "isinstance(value, __import__('logging').Logger)")
+		assertPython(t, logger, "repr(value) == '<py4go.Logger py4go.test>'")
+		assertPython(t, logger, "not value.isEnabledFor(10) and value.isEnabledFor(20)")
+
+		execLogging(t, logger, `
+def log():
+    logger.debug('ignored')
+    logger.info('hello %s', 'world', extra={'user': 'alice', 'count': 3, 'big': 2**70, 'nested': {1: [1.5, None, (True,)]}, 'other': object, '_private': 1})
+log()
+`)
+
+		if len(handler.records) != 1 {
+			t.Fatalf("expected 1 record, got %d", len(handler.records))
+		}
+
+		record := handler.records[0]
+		if (record.Message != "hello world") || (record.Level != slog.LevelInfo) || record.Time.IsZero() {
+			t.Errorf("unexpected record %v", record)
+		}
+
+		attrs := getSlogAttrs(record)
+		if attrs["logger"] != "py4go.test" {
+			t.Errorf("unexpected logger %v", attrs["logger"])
+		}
+		if source, ok := attrs[slog.SourceKey].(*slog.Source); !ok || (source.Function != "log") || !strings.HasPrefix(source.File, "<") || (source.Line != 5) {
+			t.Errorf("unexpected source %#v", attrs[slog.SourceKey])
+		}
+		if (attrs["user"] != "alice") || (attrs["count"] != int64(3)) || (attrs["big"] != "1180591620717411303424") || (attrs["other"] != "<class 'object'>") {
+			t.Errorf("unexpected extra %v", attrs)
+		}
+		if nested, ok := attrs["nested"].(map[string]interface{}); ok {
+			if list, ok := nested["1"].([]interface{}); !ok || (len(list) != 3) || (list[0] != 1.5) || (list[1] != nil) || (list[2].([]interface{})[0] != true) {
+				t.Errorf("unexpected nested %v", nested)
+			}
+		} else {
+			t.Errorf("unexpected nested %v", attrs["nested"])
+		}
+		if _, ok := attrs["_private"]; ok {
+			t.Error("expected no _private")
+		}
+		if _, ok := attrs["exception"]; ok {
+			t.Error("expected no exception")
+		}
+
+		// Does not propagate to the root logger, and disabling still works
+		assertPython(t, logger, "not value.propagate")
+		execLogging(t, logger, "logger.disabled = True\nlogger.error('ignored')\nlogger.disabled = False\nlogging.disable(logging.ERROR)\nlogger.error('ignored')\nlogging.disable(logging.NOTSET)\n")
+		if len(handler.records) != 1 {
+			t.Errorf("expected no more records, got %d", len(handler.records))
+		}
+	})
+}
+
+func TestNewGoLoggerException(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		handler := &testSlogHandler{level: slog.LevelDebug}
+		logger, err := NewGoLogger("py4go.test", slog.New(handler))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer logger.Release()
+
+		execLogging(t, logger, "try:\n    1 / 0\nexcept ZeroDivisionError:\n    logger.exception('failed', stack_info=True)\nlogger.critical('critical')\n")
+
+		if len(handler.records) != 2 {
+			t.Fatalf("expected 2 records, got %d", len(handler.records))
+		}
+
+		record := handler.records[0]
+		if (record.Message != "failed") || (record.Level != slog.LevelError) {
+			t.Errorf("unexpected record %v", record)
+		}
+		attrs := getSlogAttrs(record)
+		if exception, _ := attrs["exception"].(string); !strings.HasPrefix(exception, "Traceback (most recent call last):") || !strings.HasSuffix(exception, "ZeroDivisionError: division by zero") {
+			t.Errorf("unexpected exception %q", exception)
+		}
+		if stack, _ := attrs["stack"].(string); !strings.HasPrefix(stack, "Stack (most recent call last):") {
+			t.Errorf("unexpected stack %q", stack)
+		}
+
+		if level := handler.records[1].Level; level != slog.LevelError+4 {
+			t.Errorf("expected ERROR+4, got %s", level)
+		}
+	})
+}
+
+func TestAddLoggingHandler(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		handler := &testSlogHandler{level: slog.LevelWarn}
+		handler_, err := AddLoggingHandler("py4go.test.add", handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer handler_.Release()
+
+		logger, err := getLogger("py4go.test.add")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer logger.Release()
+		defer callLoggingMethod(logger, "removeHandler", handler_)
+
+		assertPython(t, handler_, "isinstance(value, __import__('logging').Handler)")
+		assertPython(t, handler_, "repr(value) == '<py4go.Handler (NOTSET)>'")
+
+		// The Python logger's level applies, then the slog handler's
+		execLogging(t, logger, "logger.setLevel(logging.INFO)\nlogger.debug('ignored')\nlogger.info('ignored')\nlogger.warning('first')\n")
+
+		// Filters apply
+		execLogging(t, logger, "logger.handlers[0].addFilter(lambda record: record.getMessage() != 'filtered')\nlogger.warning('filtered')\nlogger.error('second %d', 2)\n")
+
+		if len(handler.records) != 2 {
+			t.Fatalf("expected 2 records, got %d", len(handler.records))
+		}
+		for index, message := range []string{"first", "second 2"} {
+			if handler.records[index].Message != message {
+				t.Errorf("expected %q, got %q", message, handler.records[index].Message)
+			}
+		}
+	})
+}
+
+func TestLoggingHandlerError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		handler := &testSlogHandler{err: errors.New("cannot handle")}
+		logger, err := NewGoLogger("py4go.test", slog.New(handler))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer logger.Release()
+
+		// Reported via handleError, like other logging handlers do
+		_, stderr, err := CaptureOutput(func() {
+			execLogging(t, logger, "logger.info('hello')")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(stderr, "--- Logging error ---\n") || !strings.Contains(stderr, "RuntimeError: cannot handle") || !strings.Contains(stderr, "Message: 'hello'") {
+			t.Errorf("unexpected stderr %q", stderr)
+		}
+	})
+}
+
+func TestNewGoLoggerInterpreter(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		interpreter := newTestInterpreter(t)
+		defer interpreter.Close()
+
+		handler := &testSlogHandler{level: slog.LevelInfo}
+		if err := interpreter.Do(func() error {
+			// The classes belong to the interpreter
+			if logger, err := NewGoLogger("py4go.test", slog.New(handler)); err == nil {
+				defer logger.Release()
+
+				assertPython(t, logger, "isinstance(value, __import__('logging').Logger)")
+				execLogging(t, logger, "logger.info('sub')")
+				return nil
+			} else {
+				return err
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if (len(handler.records) != 1) || (handler.records[0].Message != "sub") {
+			t.Errorf("unexpected records %v", handler.records)
+		}
+	})
+}
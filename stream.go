@@ -0,0 +1,412 @@
+package python
+
+// See:
+//   https://docs.python.org/3/library/io.html
+//   https://docs.python.org/3/library/sys.html#sys.stdout
+//   https://docs.python.org/3/library/io.html#io.RawIOBase
+//   https://docs.python.org/3/c-api/buffer.html
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+// Maximum number of consecutive reads of 0 bytes from a redirected stdin reader
+const MAX_EMPTY_READS = 100
+
+//
+// Redirect
+//
+
+// A replacement of sys.stdout, sys.stderr, or sys.stdin
+type Redirect struct {
+	Name     string // "stdout", "stderr", or "stdin"
+	Stream   *Reference
+	Previous *Reference // nil if it was not set
+}
+
+// Replaces sys.stdout with a text stream that writes UTF-8 to the writer. The stream is line
+// buffered and flushing it will call the writer's Flush method if it has one.
+//
+// The writer is called while holding the GIL.
+func RedirectStdout(writer io.Writer) (*Redirect, error) {
+	return redirectWriter("stdout", writer)
+}
+
+// Replaces sys.stderr with a text stream that writes UTF-8 to the writer. The stream is line
+// buffered and flushing it will call the writer's Flush method if it has one.
+//
+// The writer is called while holding the GIL.
+func RedirectStderr(writer io.Writer) (*Redirect, error) {
+	return redirectWriter("stderr", writer)
+}
+
+// Replaces sys.stdin with a text stream that reads UTF-8 from the reader.
+//
+// The reader is called while holding the GIL.
+func RedirectStdin(reader io.Reader) (*Redirect, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	class, err := getHelperGoClass("py4go.RawReader", (*rawReader)(nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := scope.Track(class.NewObject(&rawReader{
+		Name:   "<stdin>",
+		reader: reader,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	buffered, err := scope.Track(callModuleAttr("io", "BufferedReader", []interface{}{raw}, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	if stream, err := callModuleAttr("io", "TextIOWrapper", []interface{}{buffered}, map[string]interface{}{
+		"encoding": "utf-8",
+	}); err == nil {
+		return newRedirect("stdin", stream)
+	} else {
+		return nil, err
+	}
+}
+
+// Calls the function while capturing everything written to sys.stdout and sys.stderr. The
+// original streams are restored afterwards, even if the function panics.
+//
+// Useful for testing.
+func CaptureOutput(f func()) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+
+	stdoutRedirect, err := RedirectStdout(&stdout)
+	if err != nil {
+		return "", "", err
+	}
+
+	stderrRedirect, err := RedirectStderr(&stderr)
+	if err != nil {
+		stdoutRedirect.Restore()
+		return "", "", err
+	}
+
+	err = func() error {
+		defer stdoutRedirect.Restore()
+		defer stderrRedirect.Restore()
+
+		f()
+
+		// Restore would flush, too, but we want to know about errors
+		if err := stdoutRedirect.Flush(); err != nil {
+			return err
+		}
+		return stderrRedirect.Flush()
+	}()
+
+	return stdout.String(), stderr.String(), err
+}
+
+// Flushes the stream
+func (self *Redirect) Flush() error {
+	if flush, err := self.Stream.GetAttr("flush"); err == nil {
+		defer flush.Release()
+
+		if r, err := flush.Call(); err == nil {
+			r.Release()
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Flushes the stream and restores the previous one. Does nothing if the stream has since been
+// replaced by something else.
+func (self *Redirect) Restore() error {
+	if self.Stream == nil {
+		return nil
+	}
+
+	name := C.CString(self.Name)
+	defer C.free(unsafe.Pointer(name))
+
+	var err error
+	if self.Name != "stdin" {
+		err = self.Flush()
+	}
+
+	// Borrowed reference
+	if C.PySys_GetObject(name) == self.Stream.Object {
+		var previous *C.PyObject
+		if self.Previous != nil {
+			previous = self.Previous.Object
+		}
+
+		// Deletes the attribute if previous is NULL
+		if (C.PySys_SetObject(name, previous) != 0) && (err == nil) {
+			err = GetError()
+		}
+	}
+
+	self.Stream.Release()
+	self.Stream = nil
+	if self.Previous != nil {
+		self.Previous.Release()
+		self.Previous = nil
+	}
+
+	return err
+}
+
+func redirectWriter(name string, writer io.Writer) (*Redirect, error) {
+	scope := NewScope()
+	defer scope.Close()
+
+	class, err := getHelperGoClass("py4go.RawWriter", (*rawWriter)(nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := scope.Track(class.NewObject(&rawWriter{
+		Name:   "<" + name + ">",
+		writer: writer,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Our raw writer never writes partially, so we do not need an io.BufferedWriter
+	if stream, err := callModuleAttr("io", "TextIOWrapper", []interface{}{raw}, map[string]interface{}{
+		"encoding":       "utf-8",
+		"errors":         "backslashreplace",
+		"line_buffering": true,
+		"write_through":  true,
+	}); err == nil {
+		return newRedirect(name, stream)
+	} else {
+		return nil, err
+	}
+}
+
+// Steals the stream reference
+func newRedirect(name string, stream *Reference) (*Redirect, error) {
+	name_ := C.CString(name)
+	defer C.free(unsafe.Pointer(name_))
+
+	self := Redirect{
+		Name:   name,
+		Stream: stream,
+	}
+
+	// Borrowed reference
+	if previous := C.PySys_GetObject(name_); previous != nil {
+		self.Previous = newAcquiredReference(previous)
+	}
+
+	if C.PySys_SetObject(name_, stream.Object) == 0 {
+		return &self, nil
+	} else {
+		err := GetError()
+		stream.Release()
+		if self.Previous != nil {
+			self.Previous.Release()
+		}
+		return nil, err
+	}
+}
+
+//
+// rawWriter
+//
+
+// A Python raw binary stream that writes to an io.Writer
+type rawWriter struct {
+	Name   string
+	Closed bool
+
+	writer io.Writer
+}
+
+// fmt.Stringer interface
+func (self *rawWriter) String() string {
+	return fmt.Sprintf("<py4go.RawWriter %q>", self.Name)
+}
+
+func (self *rawWriter) Readable() bool {
+	return false
+}
+
+func (self *rawWriter) Writable() bool {
+	return true
+}
+
+func (self *rawWriter) Seekable() bool {
+	return false
+}
+
+func (self *rawWriter) Isatty() bool {
+	return false
+}
+
+func (self *rawWriter) Fileno() error {
+	return newUnsupportedOperation("fileno")
+}
+
+// Accepts any object supporting the buffer protocol. Unlike io.RawIOBase we always write
+// everything (or fail), because io.TextIOWrapper ignores partial writes.
+func (self *rawWriter) Write(b *Reference) (int, error) {
+	var data []byte
+	if err := withBuffer(b, false, func(buffer []byte) error {
+		data = append([]byte(nil), buffer...)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for written < len(data) {
+		count, err := self.writer.Write(data[written:])
+		written += count
+		if err != nil {
+			return written, err
+		} else if count == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+
+	return written, nil
+}
+
+func (self *rawWriter) Flush() error {
+	if self.Closed {
+		return nil
+	}
+
+	// E.g. *bufio.Writer
+	if flusher, ok := self.writer.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	} else {
+		return nil
+	}
+}
+
+func (self *rawWriter) Close() error {
+	if self.Closed {
+		return nil
+	}
+
+	err := self.Flush()
+	self.Closed = true
+	return err
+}
+
+//
+// rawReader
+//
+
+// A Python raw binary stream that reads from an io.Reader
+type rawReader struct {
+	Name   string
+	Closed bool
+
+	reader io.Reader
+}
+
+// fmt.Stringer interface
+func (self *rawReader) String() string {
+	return fmt.Sprintf("<py4go.RawReader %q>", self.Name)
+}
+
+func (self *rawReader) Readable() bool {
+	return true
+}
+
+func (self *rawReader) Writable() bool {
+	return false
+}
+
+func (self *rawReader) Seekable() bool {
+	return false
+}
+
+func (self *rawReader) Isatty() bool {
+	return false
+}
+
+func (self *rawReader) Fileno() error {
+	return newUnsupportedOperation("fileno")
+}
+
+// Reads directly into the writable buffer. Returns 0 on EOF.
+//
+// A read of 0 bytes without an error does not mean EOF, so we try again (like bufio does) up to
+// MAX_EMPTY_READS times.
+func (self *rawReader) Readinto(b *Reference) (int, error) {
+	var count int
+	err := withBuffer(b, true, func(buffer []byte) error {
+		for tries := 0; tries < MAX_EMPTY_READS; tries++ {
+			var err error
+			if count, err = self.reader.Read(buffer); err == io.EOF {
+				return nil
+			} else if (count > 0) || (err != nil) {
+				return err
+			}
+		}
+		return io.ErrNoProgress
+	})
+	return count, err
+}
+
+// io.BufferedReader uses it to read until EOF
+func (self *rawReader) Readall() ([]byte, error) {
+	return io.ReadAll(self.reader)
+}
+
+func (self *rawReader) Flush() {
+}
+
+func (self *rawReader) Close() {
+	self.Closed = true
+}
+
+// Calls the function with the memory of an object supporting the buffer protocol, which is only
+// valid during the call. The function is not called if the buffer is empty.
+func withBuffer(reference *Reference, writable bool, f func([]byte) error) error {
+	flags := C.int(C.PyBUF_SIMPLE)
+	if writable {
+		flags = C.PyBUF_WRITABLE
+	}
+
+	var view C.Py_buffer
+	if C.PyObject_GetBuffer(reference.Object, &view, flags) != 0 {
+		return GetError()
+	}
+	defer C.PyBuffer_Release(&view)
+
+	if view.len > 0 {
+		return f(unsafe.Slice((*byte)(view.buf), int(view.len)))
+	} else {
+		return nil
+	}
+}
+
+func newUnsupportedOperation(message string) error {
+	if value, err := callModuleAttr("io", "UnsupportedOperation", []interface{}{message}, nil); err == nil {
+		return newExceptionFromValue(value.steal(), 0)
+	} else {
+		return err
+	}
+}
@@ -0,0 +1,237 @@
+package python
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCaptureOutput(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		stdout, stderr, err := CaptureOutput(func() {
+			if err := Exec("import sys\nprint('hello', 'ünï')\nprint('oops', file=sys.stderr)\nsys.stdout.write('no newline')\n", nil, nil); err != nil {
+				t.Error(err)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stdout != "hello ünï\nno newline" {
+			t.Errorf("unexpected stdout %q", stdout)
+		}
+		if stderr != "oops\n" {
+			t.Errorf("unexpected stderr %q", stderr)
+		}
+
+		// Restored
+		assertPython(t, None, "__import__('sys').stdout is __import__('sys').__stdout__")
+	})
+}
+
+func TestCaptureOutputPanic(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+
+			CaptureOutput(func() {
+				panic("oops")
+			})
+		}()
+
+		assertPython(t, None, "__import__('sys').stdout is __import__('sys').__stdout__")
+		assertPython(t, None, "__import__('sys').stderr is __import__('sys').__stderr__")
+	})
+}
+
+func TestRedirectStdout(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		var buffer bytes.Buffer
+		writer := bufio.NewWriter(&buffer)
+
+		redirect, err := RedirectStdout(writer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Exec("import sys\nsys.stdout.write('partial')\n", nil, nil); err != nil {
+			t.Error(err)
+		}
+		if buffer.Len() != 0 {
+			t.Errorf("expected the bufio.Writer to buffer, got %q", buffer.String())
+		}
+
+		// Flushes the bufio.Writer, too
+		if err := redirect.Flush(); err != nil {
+			t.Error(err)
+		}
+		if buffer.String() != "partial" {
+			t.Errorf("unexpected %q", buffer.String())
+		}
+
+		if err := redirect.Restore(); err != nil {
+			t.Error(err)
+		}
+
+		// A no-op
+		if err := redirect.Restore(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestRedirectReplaced(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		first, err := RedirectStderr(io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buffer bytes.Buffer
+		second, err := RedirectStderr(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Does not restore, because it was replaced
+		if err := first.Restore(); err != nil {
+			t.Error(err)
+		}
+		if err := Exec("import sys\nprint('second', file=sys.stderr)\n", nil, nil); err != nil {
+			t.Error(err)
+		}
+		if buffer.String() != "second\n" {
+			t.Errorf("unexpected %q", buffer.String())
+		}
+
+		if err := second.Restore(); err != nil {
+			t.Error(err)
+		}
+
+		// Restores the stream that first replaced, which is closed
+		assertPython(t, None, "__import__('sys').stderr is not __import__('sys').__stderr__")
+		if err := Exec("import sys\nsys.stderr = sys.__stderr__\n", nil, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Returns no data (and no error) every other read
+type testSlowReader struct {
+	reader io.Reader
+	empty  bool
+}
+
+// io.Reader interface
+func (self *testSlowReader) Read(p []byte) (int, error) {
+	self.empty = !self.empty
+	if self.empty {
+		return 0, nil
+	}
+	if len(p) > 2 {
+		p = p[:2]
+	}
+	return self.reader.Read(p)
+}
+
+// Never returns data
+type testStuckReader struct{}
+
+// io.Reader interface
+func (self testStuckReader) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestRedirectStdin(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, reader := range []io.Reader{
+			strings.NewReader("first line\nünï\nrest\n"),
+			&testSlowReader{reader: strings.NewReader("first line\nünï\nrest\n")},
+		} {
+			redirect, err := RedirectStdin(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := eval(t, "(input(), __import__('sys').stdin.readline(), __import__('sys').stdin.read())", nil)
+			assertPython(t, result, "value == ('first line', 'ünï\\n', 'rest\\n')")
+			result.Release()
+
+			if err := redirect.Restore(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+}
+
+func TestRedirectStdinNoProgress(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		redirect, err := RedirectStdin(testStuckReader{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer redirect.Restore()
+
+		globals := newGlobals(t, nil)
+		defer globals.Release()
+
+		if result, err := Eval("input()", globals, nil); err == nil {
+			result.Release()
+			t.Error("expected an error")
+		} else {
+			if !strings.Contains(err.Error(), io.ErrNoProgress.Error()) {
+				t.Errorf("unexpected error %v", err)
+			}
+			releaseError(err)
+		}
+	})
+}
+
+// Always fails
+type testFailingWriter struct{}
+
+// io.Writer interface
+func (self testFailingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestRedirectWriteError(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		redirect, err := RedirectStdout(testFailingWriter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Exec("print('x')", nil, nil); err == nil {
+			t.Error("expected an error")
+		} else {
+			if !errors.Is(err, ErrRuntimeError) || !strings.Contains(err.Error(), "failed") {
+				t.Errorf("unexpected error %v", err)
+			}
+			releaseError(err)
+		}
+
+		redirect.Restore()
+	})
+}
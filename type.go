@@ -25,3 +25,7 @@ func (self *Type) IsSubtype(type_ *Type) bool {
 func (self *Type) HasFlag(flag C.ulong) bool {
 	return C.PyType_GetFlags(self.Object)&flag != 0
 }
+
+func (self *Type) Name() string {
+	return C.GoString(self.Object.tp_name)
+}
@@ -0,0 +1,530 @@
+package python
+
+// See:
+//   https://docs.python.org/3/c-api/concrete.html
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unsafe"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+const UNMARSHAL_ROOT = "result"
+
+// Decodes a Python object into the Go value pointed to by dest, which is the reverse of
+// NewReferenceFromValue:
+//
+// None becomes nil, dicts can be decoded into maps or structs (matching keys by "py" tag, or else by
+// field name, field name in snake case, or field name in lower case), lists and tuples can be
+// decoded into slices or arrays, bytes can be decoded into []byte or [N]byte, and other objects can
+// be decoded into structs via their attributes. Decoding into *Reference acquires a new reference
+// and into interface{} chooses the natural Go type.
+//
+// Errors are *UnmarshalError with a path to the failing value, e.g. `result.items[3].name` (with
+// `["key"]` for map keys).
+func Unmarshal(reference *Reference, dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if (value.Kind() != reflect.Ptr) || value.IsNil() {
+		return fmt.Errorf("unmarshal destination must be a non-nil pointer: %T", dest)
+	}
+
+	return unmarshal(reference, value.Elem(), UNMARSHAL_ROOT)
+}
+
+func (self *Reference) Unmarshal(dest interface{}) error {
+	return Unmarshal(self, dest)
+}
+
+//
+// UnmarshalError
+//
+
+type UnmarshalError struct {
+	Path    string
+	Message string
+	Cause   error
+}
+
+func newUnmarshalTypeError(path string, expected string, reference *Reference) *UnmarshalError {
+	return &UnmarshalError{
+		Path:    path,
+		Message: fmt.Sprintf("expected %s, got %s", expected, reference.Type().Name()),
+	}
+}
+
+// error signature
+func (self *UnmarshalError) Error() string {
+	if self.Cause != nil {
+		return fmt.Sprintf("%s: %s: %s", self.Path, self.Message, self.Cause)
+	} else {
+		return fmt.Sprintf("%s: %s", self.Path, self.Message)
+	}
+}
+
+// errors.Unwrap signature
+func (self *UnmarshalError) Unwrap() error {
+	return self.Cause
+}
+
+// References acquired for *Reference and interface{} destinations normally belong to the
+// destination. With "track" they are also collected so that they can be released together.
+type unmarshaler struct {
+	track    bool
+	acquired []*Reference
+}
+
+func unmarshal(reference *Reference, value reflect.Value, path string) error {
+	return new(unmarshaler).unmarshal(reference, value, path)
+}
+
+// Releases the tracked references
+func (self *unmarshaler) release() {
+	for _, reference := range self.acquired {
+		reference.Release()
+	}
+	self.acquired = nil
+}
+
+func (self *unmarshaler) acquire(reference *Reference) *Reference {
+	reference_ := newAcquiredReference(reference.Object)
+	if self.track {
+		self.acquired = append(self.acquired, reference_)
+	}
+	return reference_
+}
+
+func (self *unmarshaler) unmarshal(reference *Reference, value reflect.Value, path string) error {
+	type_ := value.Type()
+
+	if type_ == referenceType {
+		value.Set(reflect.ValueOf(self.acquire(reference)))
+		return nil
+	}
+
+	if goValue, ok := reference.getGoObjectValue(); ok {
+		if goValue.Type().AssignableTo(type_) {
+			value.Set(goValue)
+			return nil
+		} else if goValue.Type().Elem().AssignableTo(type_) {
+			value.Set(goValue.Elem())
+			return nil
+		}
+	}
+
+	isNone := reference.Object == C.Py_None
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if isNone {
+			value.Set(reflect.Zero(type_))
+			return nil
+		}
+		if value.IsNil() {
+			value.Set(reflect.New(type_.Elem()))
+		}
+		return self.unmarshal(reference, value.Elem(), path)
+
+	case reflect.Interface:
+		if type_.NumMethod() != 0 {
+			return &UnmarshalError{Path: path, Message: fmt.Sprintf("unsupported interface type %s", type_)}
+		}
+		if isNone {
+			value.Set(reflect.Zero(type_))
+			return nil
+		}
+		if generic, err := self.unmarshalGeneric(reference, path); err == nil {
+			value.Set(reflect.ValueOf(generic))
+			return nil
+		} else {
+			return err
+		}
+
+	case reflect.Bool:
+		if reference.IsBool() {
+			value.SetBool(reference.ToBool())
+			return nil
+		} else {
+			return newUnmarshalTypeError(path, "bool", reference)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if reference.IsLong() && !reference.IsBool() {
+			var overflow C.int
+			if long := C.PyLong_AsLongLongAndOverflow(reference.Object, &overflow); HasException() {
+				return &UnmarshalError{Path: path, Message: "invalid int", Cause: GetError()}
+			} else if (overflow != 0) || value.OverflowInt(int64(long)) {
+				return &UnmarshalError{Path: path, Message: fmt.Sprintf("int overflows %s", type_)}
+			} else {
+				value.SetInt(int64(long))
+				return nil
+			}
+		} else {
+			return newUnmarshalTypeError(path, "int", reference)
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if reference.IsLong() && !reference.IsBool() {
+			if long := C.PyLong_AsUnsignedLongLong(reference.Object); HasException() {
+				C.PyErr_Clear()
+				return &UnmarshalError{Path: path, Message: fmt.Sprintf("int overflows %s", type_)}
+			} else if value.OverflowUint(uint64(long)) {
+				return &UnmarshalError{Path: path, Message: fmt.Sprintf("int overflows %s", type_)}
+			} else {
+				value.SetUint(uint64(long))
+				return nil
+			}
+		} else {
+			return newUnmarshalTypeError(path, "int", reference)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if reference.IsFloat() || (reference.IsLong() && !reference.IsBool()) {
+			if float, err := reference.ToFloat64(); err == nil {
+				value.SetFloat(float)
+				return nil
+			} else {
+				return &UnmarshalError{Path: path, Message: "invalid float", Cause: err}
+			}
+		} else {
+			return newUnmarshalTypeError(path, "float", reference)
+		}
+
+	case reflect.String:
+		if reference.IsUnicode() {
+			if string_, err := reference.ToString(); err == nil {
+				value.SetString(string_)
+				return nil
+			} else {
+				return &UnmarshalError{Path: path, Message: "invalid str", Cause: err}
+			}
+		} else {
+			return newUnmarshalTypeError(path, "str", reference)
+		}
+
+	case reflect.Slice:
+		if isNone {
+			value.Set(reflect.Zero(type_))
+			return nil
+		}
+
+		if type_.Elem().Kind() == reflect.Uint8 {
+			if bytes, err := getBytes(reference, path); err == nil {
+				value.SetBytes(bytes)
+				return nil
+			} else {
+				return err
+			}
+		}
+
+		if items, ok := getSequenceItems(reference); ok {
+			slice := reflect.MakeSlice(type_, len(items), len(items))
+			for index, item := range items {
+				if err := self.unmarshal(item, slice.Index(index), fmt.Sprintf("%s[%d]", path, index)); err != nil {
+					return err
+				}
+			}
+			value.Set(slice)
+			return nil
+		} else {
+			return newUnmarshalTypeError(path, "list", reference)
+		}
+
+	case reflect.Array:
+		if (type_.Elem().Kind() == reflect.Uint8) && (reference.IsBytes() || reference.IsByteArray()) {
+			if bytes, err := getBytes(reference, path); err == nil {
+				if len(bytes) != value.Len() {
+					return &UnmarshalError{Path: path, Message: fmt.Sprintf("expected %d bytes, got %d", value.Len(), len(bytes))}
+				}
+				reflect.Copy(value, reflect.ValueOf(bytes))
+				return nil
+			} else {
+				return err
+			}
+		}
+
+		if items, ok := getSequenceItems(reference); ok {
+			if len(items) != value.Len() {
+				return &UnmarshalError{Path: path, Message: fmt.Sprintf("expected %d items, got %d", value.Len(), len(items))}
+			}
+			for index, item := range items {
+				if err := self.unmarshal(item, value.Index(index), fmt.Sprintf("%s[%d]", path, index)); err != nil {
+					return err
+				}
+			}
+			return nil
+		} else {
+			return newUnmarshalTypeError(path, "tuple", reference)
+		}
+
+	case reflect.Map:
+		if isNone {
+			value.Set(reflect.Zero(type_))
+			return nil
+		}
+
+		if reference.IsDict() {
+			map_ := reflect.MakeMap(type_)
+			for _, item := range getDictItems(reference) {
+				itemPath := path + formatDictKey(item[0])
+				key := reflect.New(type_.Key()).Elem()
+				if err := self.unmarshal(item[0], key, itemPath); err != nil {
+					return err
+				}
+				element := reflect.New(type_.Elem()).Elem()
+				if err := self.unmarshal(item[1], element, itemPath); err != nil {
+					return err
+				}
+				map_.SetMapIndex(key, element)
+			}
+			value.Set(map_)
+			return nil
+		} else {
+			return newUnmarshalTypeError(path, "dict", reference)
+		}
+
+	case reflect.Struct:
+		if reference.IsDict() {
+			return self.unmarshalStructFromDict(reference, value, path)
+		} else if isNone {
+			return newUnmarshalTypeError(path, "dict", reference)
+		} else {
+			return self.unmarshalStructFromAttributes(reference, value, path)
+		}
+	}
+
+	return &UnmarshalError{Path: path, Message: fmt.Sprintf("unsupported type %s", type_)}
+}
+
+func (self *unmarshaler) unmarshalGeneric(reference *Reference, path string) (interface{}, error) {
+	switch {
+	case reference.IsBool():
+		return reference.ToBool(), nil
+
+	case reference.IsLong():
+		var value int64
+		err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+		return value, err
+
+	case reference.IsFloat():
+		var value float64
+		err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+		return value, err
+
+	case reference.IsUnicode():
+		var value string
+		err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+		return value, err
+
+	case reference.IsBytes(), reference.IsByteArray():
+		var value []byte
+		err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+		return value, err
+
+	case reference.IsList(), reference.IsTuple():
+		var value []interface{}
+		err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+		return value, err
+
+	case reference.IsDict():
+		stringKeys := true
+		for _, item := range getDictItems(reference) {
+			if !item[0].IsUnicode() {
+				stringKeys = false
+				break
+			}
+		}
+
+		if stringKeys {
+			var value map[string]interface{}
+			err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+			return value, err
+		} else {
+			var value map[interface{}]interface{}
+			err := self.unmarshal(reference, reflect.ValueOf(&value).Elem(), path)
+			return value, err
+		}
+	}
+
+	// Anything else remains a Python object
+	return self.acquire(reference), nil
+}
+
+func (self *unmarshaler) unmarshalStructFromDict(reference *Reference, value reflect.Value, path string) error {
+	return forEachStructField(value, func(name string, field reflect.StructField, fieldValue reflect.Value) error {
+		candidates := []string{name}
+		if field.Tag.Get(TAG) == "" {
+			candidates = append(candidates, toSnakeCase(name), strings.ToLower(name))
+		}
+
+		for _, candidate := range candidates {
+			candidate_ := C.CString(candidate)
+			// Borrowed reference
+			item := C.PyDict_GetItemString(reference.Object, candidate_)
+			C.free(unsafe.Pointer(candidate_))
+
+			if item != nil {
+				return self.unmarshal(NewBorrowedReference(item), fieldValue, path+"."+candidate)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (self *unmarshaler) unmarshalStructFromAttributes(reference *Reference, value reflect.Value, path string) error {
+	return forEachStructField(value, func(name string, field reflect.StructField, fieldValue reflect.Value) error {
+		candidates := []string{name}
+		if field.Tag.Get(TAG) == "" {
+			candidates = append(candidates, toSnakeCase(name))
+		}
+
+		for _, candidate := range candidates {
+			candidate_ := C.CString(candidate)
+			hasAttr := C.PyObject_HasAttrString(reference.Object, candidate_) != 0
+			C.free(unsafe.Pointer(candidate_))
+
+			if hasAttr {
+				if attr, err := reference.GetAttr(candidate); err == nil {
+					defer attr.Release()
+					return self.unmarshal(attr, fieldValue, path+"."+candidate)
+				} else {
+					return &UnmarshalError{Path: path + "." + candidate, Message: "invalid attribute", Cause: err}
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func forEachStructField(value reflect.Value, f func(name string, field reflect.StructField, fieldValue reflect.Value) error) error {
+	type_ := value.Type()
+	for index := 0; index < type_.NumField(); index++ {
+		field := type_.Field(index)
+		fieldValue := value.Field(index)
+
+		if field.Anonymous && (field.Tag.Get(TAG) == "") {
+			// Flatten embedded structs
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				if fieldValue.Kind() == reflect.Ptr {
+					if fieldValue.IsNil() {
+						if !fieldValue.CanSet() {
+							continue
+						}
+						fieldValue.Set(reflect.New(embeddedType))
+					}
+					fieldValue = fieldValue.Elem()
+				}
+				if err := forEachStructField(fieldValue, f); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			// Unexported
+			continue
+		}
+
+		if name, _, skip := parseFieldTag(field); !skip {
+			if err := f(name, field, fieldValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getBytes(reference *Reference, path string) ([]byte, error) {
+	var bytes []byte
+	var err error
+	if reference.IsBytes() {
+		bytes, err = reference.ToBytes()
+	} else if reference.IsByteArray() {
+		bytes, err = reference.ByteArrayToBytes()
+	} else {
+		return nil, newUnmarshalTypeError(path, "bytes", reference)
+	}
+
+	if err == nil {
+		return bytes, nil
+	} else {
+		return nil, &UnmarshalError{Path: path, Message: "invalid bytes", Cause: err}
+	}
+}
+
+// Returned references are borrowed
+func getSequenceItems(reference *Reference) ([]*Reference, bool) {
+	if reference.IsList() {
+		size := int(C.PyList_Size(reference.Object))
+		items := make([]*Reference, size)
+		for index := 0; index < size; index++ {
+			items[index] = NewBorrowedReference(C.PyList_GetItem(reference.Object, C.Py_ssize_t(index)))
+		}
+		return items, true
+	} else if reference.IsTuple() {
+		size := int(C.PyTuple_Size(reference.Object))
+		items := make([]*Reference, size)
+		for index := 0; index < size; index++ {
+			items[index] = NewBorrowedReference(C.PyTuple_GetItem(reference.Object, C.Py_ssize_t(index)))
+		}
+		return items, true
+	} else {
+		return nil, false
+	}
+}
+
+// Returned references are borrowed
+func getDictItems(reference *Reference) [][2]*Reference {
+	var items [][2]*Reference
+	var position C.Py_ssize_t
+	var key, value *C.PyObject
+	for C.PyDict_Next(reference.Object, &position, &key, &value) != 0 {
+		items = append(items, [2]*Reference{NewBorrowedReference(key), NewBorrowedReference(value)})
+	}
+	return items
+}
+
+func formatDictKey(key *Reference) string {
+	if key.IsUnicode() {
+		if key_, err := key.ToString(); err == nil {
+			return fmt.Sprintf("[%q]", key_)
+		}
+	}
+	if repr, err := key.Repr(); err == nil {
+		defer repr.Release()
+		return fmt.Sprintf("[%s]", repr.String())
+	}
+	return "[?]"
+}
+
+// E.g. "HTTPServer" to "http_server"
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for index, rune_ := range runes {
+		if unicode.IsUpper(rune_) {
+			if (index > 0) && ((unicode.IsLower(runes[index-1]) || unicode.IsDigit(runes[index-1])) ||
+				((index+1 < len(runes)) && unicode.IsLower(runes[index+1]) && unicode.IsUpper(runes[index-1]))) {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(unicode.ToLower(rune_))
+		} else {
+			builder.WriteRune(rune_)
+		}
+	}
+	return builder.String()
+}
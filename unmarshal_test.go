@@ -0,0 +1,164 @@
+package python
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testItem struct {
+	Name  string
+	Count int
+}
+
+type testResult struct {
+	Items []testItem
+	Meta  map[string]struct{ Hash int }
+	Pair  [2]string
+	Extra *testItem
+	Any   interface{}
+}
+
+func TestUnmarshalNested(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		value := eval(t, `{
+			"items": [{"name": "a", "count": 1}, {"Name": "b"}],
+			"meta": {"k": {"hash": 7}},
+			"pair": ("x", "y"),
+			"extra": None,
+			"any": [1, "two", {"three": 3.0}],
+		}`, nil)
+		defer value.Release()
+
+		var result testResult
+		if err := value.Unmarshal(&result); err == nil {
+			expected := testResult{
+				Items: []testItem{{"a", 1}, {"b", 0}},
+				Meta:  map[string]struct{ Hash int }{"k": {7}},
+				Pair:  [2]string{"x", "y"},
+				Any:   []interface{}{int64(1), "two", map[string]interface{}{"three": 3.0}},
+			}
+			if !reflect.DeepEqual(expected, result) {
+				t.Errorf("expected %#v, got %#v", expected, result)
+			}
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestUnmarshalAttributes(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		value := eval(t, "__import__('types').SimpleNamespace(name='a', count=2)", nil)
+		defer value.Release()
+
+		var item testItem
+		if err := value.Unmarshal(&item); err == nil {
+			if (item.Name != "a") || (item.Count != 2) {
+				t.Errorf("unexpected %#v", item)
+			}
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestUnmarshalReference(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		value := eval(t, "[object()]", nil)
+		defer value.Release()
+
+		var references []*Reference
+		if err := value.Unmarshal(&references); err == nil {
+			// Acquired for us
+			if len(references) != 1 {
+				t.Fatalf("unexpected %v", references)
+			}
+			references[0].Release()
+		} else {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestUnmarshalErrorPath(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		for _, test := range []struct {
+			expression string
+			path       string
+			message    string
+		}{
+			{`{"items": [{"name": "a"}, {"Name": 3}]}`, `result.items[1].Name`, "expected str, got int"},
+			{`{"meta": {"k": {"hash": "x"}}}`, `result.meta["k"].hash`, "expected int, got str"},
+			{`{"pair": ["x"]}`, `result.pair`, "expected 2 items, got 1"},
+			{`{"items": {}}`, `result.items`, "expected"},
+		} {
+			value := eval(t, test.expression, nil)
+
+			var result testResult
+			err := value.Unmarshal(&result)
+			value.Release()
+
+			var unmarshalError *UnmarshalError
+			if errors.As(err, &unmarshalError) {
+				if unmarshalError.Path != test.path {
+					t.Errorf("%s: expected path %s, got %s", test.expression, test.path, unmarshalError.Path)
+				}
+				if !strings.HasPrefix(unmarshalError.Message, test.message) {
+					t.Errorf("%s: expected message %q, got %q", test.expression, test.message, unmarshalError.Message)
+				}
+				if !strings.HasPrefix(err.Error(), test.path+": ") {
+					t.Errorf("%s: unexpected error %q", test.expression, err.Error())
+				}
+			} else {
+				t.Errorf("%s: expected an *UnmarshalError, got %v", test.expression, err)
+			}
+		}
+	})
+}
+
+func TestUnmarshalOverflow(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		value := eval(t, "[300, -1]", nil)
+		defer value.Release()
+
+		var signed []int8
+		if err := value.Unmarshal(&signed); err == nil {
+			t.Error("expected an overflow error for int8")
+		} else if !strings.Contains(err.Error(), "result[0]: int overflows int8") {
+			t.Errorf("unexpected error %q", err.Error())
+		}
+
+		var unsigned []uint16
+		if err := value.Unmarshal(&unsigned); err == nil {
+			t.Error("expected an overflow error for uint16")
+		} else if !strings.Contains(err.Error(), "result[1]: int overflows uint16") {
+			t.Errorf("unexpected error %q", err.Error())
+		}
+	})
+}
+
+func TestUnmarshalDestination(t *testing.T) {
+	CheckLeaks(t)
+
+	withGIL(t, func() {
+		value := eval(t, "1", nil)
+		defer value.Release()
+
+		var destination int
+		if err := value.Unmarshal(destination); err == nil {
+			t.Error("expected an error for a non-pointer destination")
+		}
+	})
+}
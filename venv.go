@@ -0,0 +1,139 @@
+package python
+
+// See:
+//   https://docs.python.org/3/library/venv.html
+//   https://docs.python.org/3/library/site.html
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+/*
+#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+*/
+import "C"
+
+const VIRTUAL_ENV_CONFIG = "pyvenv.cfg"
+
+var defaultVirtualEnv *VirtualEnv
+var defaultVirtualEnvLock sync.Mutex
+
+//
+// VirtualEnv
+//
+
+type VirtualEnv struct {
+	Path                      string
+	Home                      string // directory of the base interpreter's executable
+	Version                   string // of the base interpreter, e.g. "3.11.2"
+	IncludeSystemSitePackages bool
+}
+
+// Reads the virtual environment's "pyvenv.cfg" and validates that it was created for the same
+// Python major and minor version as the one we are linked against.
+func LoadVirtualEnv(path string) (*VirtualEnv, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(path, VIRTUAL_ENV_CONFIG))
+	if err != nil {
+		return nil, fmt.Errorf("not a virtual environment: %w", err)
+	}
+	defer file.Close()
+
+	self := VirtualEnv{Path: path}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key, value, ok := parseConfigLine(scanner.Text()); ok {
+			switch key {
+			case "home":
+				self.Home = value
+			case "version", "version_info":
+				self.Version = value
+			case "include-system-site-packages":
+				self.IncludeSystemSitePackages = strings.EqualFold(value, "true")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if self.Version != "" {
+		if !isSameMinorVersion(self.Version, Version()) {
+			return nil, fmt.Errorf("virtual environment %q is for Python %s but we are linked against Python %s", path, self.Version, strings.SplitN(Version(), " ", 2)[0])
+		}
+	}
+
+	return &self, nil
+}
+
+// The virtual environment's Python executable, which need not exist. Python uses its location to
+// find "pyvenv.cfg" when computing sys.prefix, sys.exec_prefix, and the site-packages directories.
+func (self *VirtualEnv) Executable() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(self.Path, "Scripts", "python.exe")
+	} else {
+		return filepath.Join(self.Path, "bin", "python3")
+	}
+}
+
+// Makes Initialize and InitializeWithConfig (unless Config.VirtualEnv is set) use the virtual
+// environment, so that Python sees the same packages as the virtual environment's python
+// executable. Must be called before initialization. An empty path stops using a virtual
+// environment.
+//
+// Note that the site-packages directories are added by the site module, so they are not added if
+// Config.SkipSiteImport is true.
+func UseVirtualEnv(path string) error {
+	if C.Py_IsInitialized() != 0 {
+		return fmt.Errorf("Python is already initialized")
+	}
+
+	var virtualEnv *VirtualEnv
+	if path != "" {
+		var err error
+		if virtualEnv, err = LoadVirtualEnv(path); err != nil {
+			return err
+		}
+	}
+
+	defaultVirtualEnvLock.Lock()
+	defer defaultVirtualEnvLock.Unlock()
+
+	defaultVirtualEnv = virtualEnv
+	return nil
+}
+
+func getDefaultVirtualEnv() *VirtualEnv {
+	defaultVirtualEnvLock.Lock()
+	defer defaultVirtualEnvLock.Unlock()
+
+	return defaultVirtualEnv
+}
+
+// Compares e.g. "3.11.2" with "3.11.7 (main, ...)"
+func isSameMinorVersion(version1 string, version2 string) bool {
+	split1 := strings.SplitN(version1, ".", 3)
+	split2 := strings.SplitN(version2, ".", 3)
+	return (len(split1) >= 2) && (len(split2) >= 2) && (split1[0] == split2[0]) && (split1[1] == split2[1])
+}
+
+// Splits a "key = value" line at the first "=" and trims both sides
+func parseConfigLine(line string) (string, string, bool) {
+	if index := strings.Index(line, "="); index >= 0 {
+		return strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+1:]), true
+	} else {
+		return "", "", false
+	}
+}